@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/abruno06/myvault/config"
 	"github.com/abruno06/myvault/securestore"
 )
 
 func main() {
 	ctx := context.Background()
-	//retreive the token from the Arg[1]
-	token := os.Args[1]
+	//the --profile flag select the profile of config.json
+	args := config.ParseFlags()
+	//retreive the token from the first argument
+	token := args[0]
 	//fmt.Printf("myvault is running with APPNAME: %s and VAULTURL: %s\n", config.ReadAPPNAME(), config.ReadVaultURL())
 	var secstore securestore.SecretStore
 	var e error
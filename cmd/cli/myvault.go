@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/abruno06/myvault/config"
@@ -13,13 +14,15 @@ import (
 	"github.com/abruno06/myvault/secret"
 	"github.com/abruno06/myvault/securestore"
 	"github.com/abruno06/myvault/smartcard"
+
+	"github.com/go-piv/piv-go/piv"
 )
 
 var VAULTURL = "https://172.0.0.1:8200"
 var APPNAME = "myapp"
 
-// display the mene
-func displayMenu() {
+// display the mene, sealed is true when the secrets are encrypted with the zero knowledge data key
+func displayMenu(sealed bool) {
 	fmt.Println("Select Action")
 	fmt.Println("1. List Secrets")
 	fmt.Println("2. Add Secret")
@@ -31,34 +34,129 @@ func displayMenu() {
 	fmt.Println("8. Generate Secret bootstrap token")
 	fmt.Println("9. Generate Secret bootstrap token (list)")
 	fmt.Println("10. Service Token bootstrap token")
-	fmt.Println("11. Exit")
+	fmt.Println("11. Secret History")
+	fmt.Println("12. Diff Secret Versions")
+	fmt.Println("13. Restore Secret Version")
+	fmt.Println("14. List Trash")
+	fmt.Println("15. Restore Secret from Trash")
+	fmt.Println("16. Purge Secret from Trash")
+	fmt.Println("17. List Folder")
+	fmt.Println("18. Tree View")
+	fmt.Println("19. Move / Rename Secret or Folder")
+	fmt.Println("20. Switch Namespace")
+	fmt.Println("21. Switch Profile")
+	fmt.Println("22. Search Secrets")
+	fmt.Println("23. List Secrets by Tag")
+	fmt.Println("24. Generate Secret bootstrap token (tag)")
+	fmt.Println("25. OTP Code")
+	fmt.Println("26. Attach File")
+	fmt.Println("27. List Attachments")
+	fmt.Println("28. Extract Attachment")
+	fmt.Println("29. Delete Attachment")
+	fmt.Println("30. Rotation Report")
+	fmt.Println("31. Breached Passwords Audit")
+	fmt.Println("32. Health Report")
+	if sealed {
+		fmt.Println("33. Encrypt Existing Secrets (zero knowledge)")
+	}
+	fmt.Println("34. Exit")
+	fmt.Print("Enter Action Number: ")
+}
+
+// display the menu available when working on the offline cache
+func displayOfflineMenu(secstore securestore.SecretStore) {
+	fmt.Println(securestore.OfflineBanner(secstore))
+	fmt.Println("Select Action")
+	fmt.Println("1. List Secrets")
+	fmt.Println("2. Get Secret")
+	fmt.Println("3. Random Password")
+	fmt.Println("4. Search Secrets")
+	fmt.Println("5. OTP Code")
+	fmt.Println("6. List Attachments")
+	fmt.Println("7. Extract Attachment")
+	fmt.Println("8. Exit")
 	fmt.Print("Enter Action Number: ")
 }
 
+// create a read only menu used when vault can not be reached
+func offlineMenu(ctx context.Context, secstore securestore.SecretStore) {
+	for {
+		displayOfflineMenu(secstore)
+		var actionNumber int
+		fmt.Scanln(&actionNumber)
+		switch actionNumber {
+		case 1:
+			fmt.Println("List Secrets")
+			securestore.ListSecrets(ctx, secstore)
+		case 2:
+			fmt.Println("Get Secret")
+			s, _ := securestore.GetSecret(ctx, secstore, interactif.AskSecret())
+			fmt.Printf("Secret ID:\n%s", s)
+		case 3:
+			interactif.RandomPassword(ctx, secstore)
+		case 4:
+			fmt.Println("Search Secrets")
+			if err := interactif.SearchInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error searching secrets: %v\n", err)
+			}
+		case 5:
+			fmt.Println("OTP Code")
+			if err := interactif.OTPCodeInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error reading OTP: %v\n", err)
+			}
+		case 6:
+			fmt.Println("List Attachments")
+			if err := interactif.ListAttachmentsInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error listing attachments: %v\n", err)
+			}
+		case 7:
+			fmt.Println("Extract Attachment")
+			if err := interactif.ExtractAttachmentInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error extracting attachment: %v\n", err)
+			}
+		default:
+			fmt.Println("Exit")
+			return
+		}
+	}
+}
+
 // create a menu based cli with option to select the action
-func menu(ctx context.Context, secstore securestore.SecretStore) {
+// true is returned when the user switched to another profile
+func menu(ctx context.Context, secstore securestore.SecretStore) bool {
 
 	//display the current token
 	for {
-		displayMenu()
+		_, sealed := secstore.Backend.(*securestore.SealedBackend)
+		displayMenu(sealed)
 		var actionNumber int
 		fmt.Scanln(&actionNumber)
+		//the zero knowledge action is only available when the store is sealed, otherwise it is an unknown action
+		if actionNumber == 33 && !sealed {
+			actionNumber = 0
+		}
 		switch actionNumber {
 		case 1:
 			fmt.Println("List Secrets")
 			securestore.ListSecrets(ctx, secstore)
 		case 2:
 			fmt.Println("Add Secret")
-			interactif.AddSecretInteractive(ctx, secstore)
+			if err := interactif.AddSecretInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error adding secret: %v\n", err)
+			}
 			securestore.ListSecrets(ctx, secstore)
 		case 3:
 			securestore.ListSecrets(ctx, secstore)
 			fmt.Println("Delete Secret")
-			interactif.DeleteSecretInteractive(ctx, secstore)
+			if err := interactif.DeleteSecretInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error deleting secret: %v\n", err)
+			}
 			securestore.ListSecrets(ctx, secstore)
 		case 4:
 			fmt.Println("Update Secret")
-			interactif.UpdateSecretInteractive(ctx, secstore)
+			if err := interactif.UpdateSecretInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error updating secret: %v\n", err)
+			}
 			securestore.ListSecrets(ctx, secstore)
 		case 5:
 			fmt.Println("Get Secret")
@@ -72,7 +170,7 @@ func menu(ctx context.Context, secstore securestore.SecretStore) {
 			readCSV(ctx, secstore, filename)
 			securestore.ListSecrets(ctx, secstore)
 		case 7:
-			interactif.RandomPassword()
+			interactif.RandomPassword(ctx, secstore)
 		case 8:
 			interactif.GenerateBootstrapToken(ctx, secstore)
 		case 9:
@@ -80,11 +178,129 @@ func menu(ctx context.Context, secstore securestore.SecretStore) {
 		case 10:
 			interactif.GenerateServiceToken(ctx, secstore)
 		case 11:
+			fmt.Println("Secret History")
+			if err := interactif.SecretHistoryInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error reading history: %v\n", err)
+			}
+		case 12:
+			fmt.Println("Diff Secret Versions")
+			if err := interactif.DiffSecretInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error comparing versions: %v\n", err)
+			}
+		case 13:
+			fmt.Println("Restore Secret Version")
+			if err := interactif.RestoreSecretInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error restoring secret: %v\n", err)
+			}
+		case 14:
+			fmt.Println("List Trash")
+			if err := securestore.DisplayTrash(ctx, secstore); err != nil {
+				fmt.Printf("Error listing trash: %v\n", err)
+			}
+		case 15:
+			fmt.Println("Restore Secret from Trash")
+			if err := interactif.RestoreTrashInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error restoring secret: %v\n", err)
+			}
+			securestore.ListSecrets(ctx, secstore)
+		case 16:
+			fmt.Println("Purge Secret from Trash")
+			if err := interactif.PurgeTrashInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error purging secret: %v\n", err)
+			}
+		case 17:
+			fmt.Println("List Folder")
+			if err := interactif.ListFolderInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error listing folder: %v\n", err)
+			}
+		case 18:
+			fmt.Println("Tree View")
+			if err := interactif.TreeInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error listing folder: %v\n", err)
+			}
+		case 19:
+			fmt.Println("Move / Rename Secret or Folder")
+			if err := interactif.MoveSecretInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error moving secret: %v\n", err)
+			}
+			securestore.ListSecrets(ctx, secstore)
+		case 20:
+			fmt.Println("Switch Namespace")
+			if switched, err := interactif.SwitchNamespaceInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error switching namespace: %v\n", err)
+			} else {
+				secstore = switched
+			}
+		case 21:
+			fmt.Println("Switch Profile")
+			if err := interactif.SwitchProfileInteractive(); err != nil {
+				fmt.Printf("Error switching profile: %v\n", err)
+			} else {
+				return true
+			}
+		case 22:
+			fmt.Println("Search Secrets")
+			if err := interactif.SearchInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error searching secrets: %v\n", err)
+			}
+		case 23:
+			fmt.Println("List Secrets by Tag")
+			if err := interactif.ListTagInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error listing secrets: %v\n", err)
+			}
+		case 24:
+			interactif.GenerateBootstrapTokenTag(ctx, secstore)
+		case 25:
+			fmt.Println("OTP Code")
+			if err := interactif.OTPCodeInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error reading OTP: %v\n", err)
+			}
+		case 26:
+			fmt.Println("Attach File")
+			if err := interactif.AttachFileInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error attaching file: %v\n", err)
+			}
+		case 27:
+			fmt.Println("List Attachments")
+			if err := interactif.ListAttachmentsInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error listing attachments: %v\n", err)
+			}
+		case 28:
+			fmt.Println("Extract Attachment")
+			if err := interactif.ExtractAttachmentInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error extracting attachment: %v\n", err)
+			}
+		case 29:
+			fmt.Println("Delete Attachment")
+			if err := interactif.DeleteAttachmentInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error deleting attachment: %v\n", err)
+			}
+		case 30:
+			fmt.Println("Rotation Report")
+			if err := interactif.RotationReportInteractive(ctx, secstore, false); err != nil {
+				fmt.Printf("Error reading rotation report: %v\n", err)
+			}
+		case 31:
+			fmt.Println("Breached Passwords Audit")
+			if err := interactif.AuditBreachedInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error auditing credentials: %v\n", err)
+			}
+		case 32:
+			fmt.Println("Health Report")
+			if err := interactif.HealthReportInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error reading health report: %v\n", err)
+			}
+		case 33:
+			fmt.Println("Encrypt Existing Secrets")
+			if err := interactif.SealExistingInteractive(ctx, secstore); err != nil {
+				fmt.Printf("Error encrypting secrets: %v\n", err)
+			}
+		case 34:
 			fmt.Println("Exit")
-			return
+			return false
 		default:
 			fmt.Println("by Default Exit")
-			return
+			return false
 		}
 
 	}
@@ -102,6 +318,10 @@ func csvToSecret(record []string) secret.Secret {
 		LastUpdate:   time.Now(),
 		LastUpdateBy: config.User,
 	}
+	//the tags column is optional
+	if len(record) > 5 {
+		rValue.Tags = secret.ParseTags(record[5])
+	}
 	return rValue
 }
 
@@ -115,11 +335,13 @@ func readCSV(ctx context.Context, secstore securestore.SecretStore, filename str
 	}
 	// Parse the file
 	r := csv.NewReader(csvfile)
+	// the tags column is optional on each record
+	r.FieldsPerRecord = -1
 	// Iterate through the records and insert them in vault. stop when EOF
 	for {
 		// Read each record from csv
 		// CSV Format is
-		// ID,Username,Credential,URL,Comment
+		// ID,Username,Credential,URL,Comment[,Tags]
 		// LastUpdate,LastUpdateBy are automatically added
 
 		record, err := r.Read()
@@ -130,33 +352,38 @@ func readCSV(ctx context.Context, secstore securestore.SecretStore, filename str
 		}
 		fmt.Printf("Record: %v\n", record)
 		//ask the Secret detail
-		securestore.AddSecret(ctx, secstore, csvToSecret(record), record[0])
+		sec := csvToSecret(record)
+		// a Credential "*" or "*policy" is generated with the password policy
+		if strings.HasPrefix(sec.Credential, "*") {
+			password, err := securestore.GeneratePassword(ctx, secstore, strings.TrimPrefix(sec.Credential, "*"))
+			if err != nil {
+				log.Printf("Secret ID: %s %v\n", record[0], err)
+				continue
+			}
+			sec.Credential = password
+		}
+		securestore.AddSecret(ctx, secstore, sec, record[0])
 
 	}
 
 }
 
-// main function
-func main() {
-	//read the configuration file
-	//fmt.Printf("config:%s")
-	//prepare the context
-	ctx := context.Background()
-	//print the default the app is running
-	fmt.Printf("%s is running with APPNAME: %s and VAULTURL: %s\n", os.Args[0], config.ReadAPPNAME(), config.ReadVaultURL())
-	var secstore securestore.SecretStore
-	var e error
-	// check yubikey is plugged in
-	if smartcard.CheckYubikey() {
-
-		yk := smartcard.OpenYubikey(interactif.SelectSmartcard())
-		defer yk.Close()
+// login to the store selected by the configuration
+// yk is the opened yubikey (nil if none is used) and pin the pin typed by the user, the caller must close yk
+func connect(ctx context.Context) (secstore securestore.SecretStore, yk *piv.YubiKey, pin string, e error) {
+	// local backends do not need a vault login
+	if config.ReadBackend() != "vault" {
+		fmt.Printf("Using %s backend\n", config.ReadBackend())
+		secstore, e = securestore.ConnectLocalStore(interactif.ReadPassphrase())
+	} else if smartcard.CheckYubikey() {
+		// yubikey is plugged in
+		yk = smartcard.OpenYubikey(interactif.SelectSmartcard())
 		cert := smartcard.ReadYubikeyCertificate(yk, smartcard.SelectSlot())
 		fmt.Printf("Certificate: %v\n", cert.PublicKeyAlgorithm)
 		//ask user pin
-		pin := interactif.ReadPin()
+		pin = interactif.ReadPin()
 		secstore, e = securestore.ConnectVaulwithYubikey(ctx, yk, pin)
-		if e != nil {
+		if e != nil && !securestore.IsUnreachable(e) {
 			fmt.Println("Bad Pin. Falling back to username and password")
 			username, password := interactif.ReadUsernamePassword()
 			secstore, e = securestore.ConnectVaultWithUsernamePassword(ctx, username, password)
@@ -168,9 +395,77 @@ func main() {
 		secstore, e = securestore.ConnectVaultWithUsernamePassword(ctx, username, password)
 
 	}
+	return secstore, yk, pin, e
+}
+
+// run a session on the selected profile, true is returned when the user switched to another profile
+func run(ctx context.Context) bool {
+	//print the default the app is running
+	if config.ReadProfile() != "" {
+		fmt.Printf("Using profile: %s\n", config.ReadProfile())
+	}
+	fmt.Printf("%s is running with APPNAME: %s and VAULTURL: %s\n", os.Args[0], config.ReadAPPNAME(), config.ReadVaultURL())
+	if config.ReadNamespace() != "" {
+		fmt.Printf("Using namespace: %s\n", config.ReadNamespace())
+	}
+	secstore, yk, pin, e := connect(ctx)
+	if yk != nil {
+		defer yk.Close()
+	}
+	// vault can not be reached, use the offline cache if enabled
+	if e != nil && config.ReadBackend() == "vault" && config.ReadCache() && securestore.IsUnreachable(e) {
+		fmt.Printf("Vault is unreachable: %v\n", e)
+		secstore, e = interactif.OpenCacheInteractive(yk, pin)
+		if e != nil {
+			log.Fatal(e)
+		}
+		offlineMenu(ctx, secstore)
+		return false
+	}
 	if e != nil {
 		log.Fatal(e)
 	}
-	menu(ctx, secstore)
+	//the secrets are encrypted with the data key of the user
+	if config.ReadZeroKnowledge() {
+		secstore, e = interactif.UnlockZeroKnowledgeInteractive(ctx, secstore, yk, pin)
+		if e != nil {
+			log.Fatal(e)
+		}
+	}
+	// keep a copy of the secrets for the next offline use, it is saved right after the login so an
+	// interrupted session still leaves a fresh cache, then again when the menu exits
+	var lock *securestore.CacheLock
+	if config.ReadBackend() == "vault" && config.ReadCache() {
+		if l, err := interactif.AskCacheLock(yk); err != nil {
+			fmt.Printf("Error saving offline cache: %v\n", err)
+		} else {
+			lock = &l
+		}
+	}
+	saveCache := func() {
+		if lock == nil {
+			return
+		}
+		if err := interactif.SaveCacheInteractive(ctx, secstore, *lock); err != nil {
+			fmt.Printf("Error saving offline cache: %v\n", err)
+		}
+	}
+	saveCache()
+	//remind the secrets to rotate
+	if err := interactif.RotationReportInteractive(ctx, secstore, true); err != nil {
+		fmt.Printf("Error reading rotation report: %v\n", err)
+	}
+	switched := menu(ctx, secstore)
+	saveCache()
+	return switched
+}
 
+// main function
+func main() {
+	//the --profile flag select the profile of config.json
+	config.ParseFlags()
+	//prepare the context
+	ctx := context.Background()
+	for run(ctx) {
+	}
 }
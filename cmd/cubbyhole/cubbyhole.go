@@ -6,24 +6,27 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/abruno06/myvault/config"
 	"github.com/abruno06/myvault/securestore"
 )
 
 // dispaly how to use the tool
 func usage() {
-	fmt.Printf("Usage: %s <token>\n", os.Args[0])
+	fmt.Printf("Usage: %s [--profile name] <token>\n", os.Args[0])
 }
 
 func main() {
 	ctx := context.Background()
-	//check if arg[1] is present
-	if len(os.Args) < 2 {
+	//the --profile flag select the profile of config.json
+	args := config.ParseFlags()
+	//check if the token is present
+	if len(args) < 1 {
 		fmt.Printf("Error: Missing token\n")
 		usage()
 		os.Exit(1)
 	}
-	//retreive the token from the Arg[1]
-	token := os.Args[1]
+	//retreive the token from the first argument
+	token := args[0]
 	var secstore securestore.SecretStore
 	var e error
 	//connect to vault using given token
@@ -32,6 +35,10 @@ func main() {
 		fmt.Printf("Error connecting to vault: %v\n", e)
 		os.Exit(1)
 	}
+	if err := securestore.CheckZeroKnowledge(secstore); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	json, err := securestore.ListCubbyhole(ctx, secstore)
 	if err != nil {
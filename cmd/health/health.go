@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this tools will report the security health of the secrets of APPNAME (reused, weak, incomplete, duplicated, stale and invalid secrets)
+var jsonOutput = flag.Bool("json", false, "print the report as JSON")
+
+func usage() {
+	fmt.Printf("Usage: %s [--profile name] [--json] <token>\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	ctx := context.Background()
+	//the --profile flag select the profile of config.json
+	args := config.ParseFlags()
+	//check if the token is present
+	if len(args) < 1 {
+		fmt.Printf("Error: Missing token\n")
+		usage()
+		os.Exit(1)
+	}
+	//connect to vault using given token
+	secstore, err := securestore.ConnectVaultWithToken(ctx, args[0])
+	if err != nil {
+		fmt.Printf("Error connecting to vault: %v\n", err)
+		os.Exit(1)
+	}
+	if err := securestore.CheckZeroKnowledge(secstore); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	report, err := securestore.HealthCheck(ctx, secstore, time.Now())
+	if err != nil {
+		fmt.Printf("Error reading secrets: %v\n", err)
+		os.Exit(1)
+	}
+	if !*jsonOutput {
+		securestore.DisplayHealthReport(report)
+		return
+	}
+	output, err := securestore.HealthReportJSON(report)
+	if err != nil {
+		fmt.Printf("Error encoding report: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(output)
+}
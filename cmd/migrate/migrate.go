@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this tools will move the secrets of APPNAME between the legacy single entry layout and the one entry per secret layout
+const ActionsList = "split,rollback"
+
+func usage() {
+	fmt.Printf("Usage: %s [--profile name] <token> <action>\n", os.Args[0])
+	fmt.Printf("action: %s\n", ActionsList)
+	fmt.Printf("  split: copy every secret of <MOUNTPATH>/<APPNAME> to <MOUNTPATH>/<APPNAME>/<ID>\n")
+	fmt.Printf("  rollback: merge <MOUNTPATH>/<APPNAME>/<ID> entries back into <MOUNTPATH>/<APPNAME>\n")
+}
+
+func main() {
+	ctx := context.Background()
+	//the --profile flag select the profile of config.json
+	args := config.ParseFlags()
+	//check if the token and the action are present
+	if len(args) < 2 {
+		fmt.Printf("Error: Missing token and/or action\n")
+		usage()
+		os.Exit(1)
+	}
+	//retreive the token from the first argument
+	token := args[0]
+	action := args[1]
+	//connect to vault using given token
+	secstore, err := securestore.ConnectVaultWithToken(ctx, token)
+	if err != nil {
+		fmt.Printf("Error connecting to vault: %v\n", err)
+		os.Exit(1)
+	}
+	if err := securestore.CheckZeroKnowledge(secstore); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch action {
+	case "split":
+		count, err := securestore.MigrateToSplit(ctx, secstore)
+		if err != nil {
+			fmt.Printf("Error migrating secrets: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d secret(s) migrated\n", count)
+	case "rollback":
+		count, err := securestore.RollbackToBlob(ctx, secstore)
+		if err != nil {
+			fmt.Printf("Error rolling back secrets: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d secret(s) rolled back\n", count)
+	default:
+		fmt.Printf("Error: Invalid action\n")
+		usage()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this tools will report the secrets of APPNAME overdue or due soon for rotation
+var jsonOutput = flag.Bool("json", false, "print the report as JSON")
+
+func usage() {
+	fmt.Printf("Usage: %s [--profile name] [--json] <token>\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	ctx := context.Background()
+	//the --profile flag select the profile of config.json
+	args := config.ParseFlags()
+	//check if the token is present
+	if len(args) < 1 {
+		fmt.Printf("Error: Missing token\n")
+		usage()
+		os.Exit(1)
+	}
+	//connect to vault using given token
+	secstore, err := securestore.ConnectVaultWithToken(ctx, args[0])
+	if err != nil {
+		fmt.Printf("Error connecting to vault: %v\n", err)
+		os.Exit(1)
+	}
+	entries, err := securestore.RotationReport(ctx, secstore, securestore.ReadRotationPolicy(), time.Now())
+	if err != nil {
+		fmt.Printf("Error reading secrets: %v\n", err)
+		os.Exit(1)
+	}
+	if !*jsonOutput {
+		securestore.DisplayRotationReport(entries)
+		return
+	}
+	report, err := securestore.RotationReportJSON(entries)
+	if err != nil {
+		fmt.Printf("Error encoding report: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(report)
+}
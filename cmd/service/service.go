@@ -5,18 +5,21 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/abruno06/myvault/config"
 	"github.com/abruno06/myvault/securestore"
 )
 
 func main() {
 	ctx := context.Background()
-	//check if arg[1] is present
-	if len(os.Args) < 2 {
+	//the --profile flag select the profile of config.json
+	args := config.ParseFlags()
+	//check if the token is present
+	if len(args) < 1 {
 		fmt.Printf("Error: Missing token\n")
 		os.Exit(1)
 	}
-	//retreive the token from the Arg[1]
-	token := os.Args[1]
+	//retreive the token from the first argument
+	token := args[0]
 	var secstore securestore.SecretStore
 	var e error
 	//connect to vault
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this tools will compare the secrets of two stores (servers, mounts or APPNAMEs) and sync them
+const ActionsList = "diff,oneway,twoway"
+
+var sourceProfile = flag.String("source-profile", "", "profile of the source store (default the selected profile)")
+var targetProfile = flag.String("target-profile", "", "profile of the target store (default the selected profile)")
+var sourceApp = flag.String("source-app", "", "APPNAME of the source store (default the APPNAME of its profile)")
+var targetApp = flag.String("target-app", "", "APPNAME of the target store (default the APPNAME of its profile)")
+var dryRun = flag.Bool("dry-run", false, "display the changes without writing them")
+var prune = flag.Bool("prune", false, "oneway: delete the target secrets missing from the source")
+
+func usage() {
+	fmt.Printf("Usage: %s [flags] <source-token> <target-token> <action>\n", os.Args[0])
+	fmt.Printf("action: %s\n", ActionsList)
+	fmt.Printf("  diff: display the added, changed and removed secrets of the target\n")
+	fmt.Printf("  oneway: copy the added and changed secrets of the source to the target\n")
+	fmt.Printf("  twoway: copy the missing secrets both ways, the latest LastUpdate win for the changed ones\n")
+	flag.PrintDefaults()
+}
+
+// connect to the store of the profile with the token, the appname replace the one of the profile if set
+func connect(ctx context.Context, profile, appname, token string) securestore.SecretStore {
+	if err := config.SetProfile(profile); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	secstore, err := securestore.ConnectVaultWithToken(ctx, token)
+	if err != nil {
+		fmt.Printf("Error connecting to vault: %v\n", err)
+		os.Exit(1)
+	}
+	if err := securestore.CheckZeroKnowledge(secstore); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if appname != "" {
+		secstore.Appname = appname
+	}
+	return secstore
+}
+
+func main() {
+	ctx := context.Background()
+	//the --profile flag select the profile of config.json
+	args := config.ParseFlags()
+	//check if the tokens and the action are present
+	if len(args) < 3 {
+		fmt.Printf("Error: Missing token and/or action\n")
+		usage()
+		os.Exit(1)
+	}
+	profile := config.ReadProfile()
+	if *sourceProfile == "" {
+		*sourceProfile = profile
+	}
+	if *targetProfile == "" {
+		*targetProfile = profile
+	}
+	source := connect(ctx, *sourceProfile, *sourceApp, args[0])
+	target := connect(ctx, *targetProfile, *targetApp, args[1])
+	fmt.Printf("Source: %s %s/%s\n", *sourceProfile, source.Mountpath, source.Appname)
+	fmt.Printf("Target: %s %s/%s\n", *targetProfile, target.Mountpath, target.Appname)
+
+	options := securestore.SyncOptions{DryRun: *dryRun, Prune: *prune}
+	switch args[2] {
+	case "diff":
+		options.DryRun = true
+	case "oneway":
+	case "twoway":
+		options.TwoWay = true
+	default:
+		fmt.Printf("Error: Invalid action\n")
+		usage()
+		os.Exit(1)
+	}
+	changes, err := securestore.SyncStores(ctx, source, target, options)
+	securestore.DisplaySyncChanges(changes)
+	if err != nil {
+		fmt.Printf("Error syncing secrets: %v\n", err)
+		os.Exit(1)
+	}
+	if options.DryRun {
+		fmt.Println("Dry run, nothing written")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/abruno06/myvault/config"
 	"github.com/abruno06/myvault/securestore"
 )
 
@@ -13,20 +14,22 @@ import (
 const ActionsList = "renew,revoke"
 
 func usage() {
-	fmt.Printf("Usage: %s <token> <action>\n", os.Args[0])
+	fmt.Printf("Usage: %s [--profile name] <token> <action>\n", os.Args[0])
 	fmt.Printf("action: %s\n", ActionsList)
 }
 func main() {
 	ctx := context.Background()
-	//check if arg[1] is present
-	if len(os.Args) < 3 {
+	//the --profile flag select the profile of config.json
+	args := config.ParseFlags()
+	//check if the token is present
+	if len(args) < 2 {
 		fmt.Printf("Error: Missing token and/or action\n")
 		usage()
 		os.Exit(1)
 	}
-	//retreive the token from the Arg[1]
-	token := os.Args[1]
-	action := os.Args[2]
+	//retreive the token from the first argument
+	token := args[0]
+	action := args[1]
 	var secstore securestore.SecretStore
 	var e error
 	//connect to vault using given token
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this tools will maintain the transit encryption of the secrets of APPNAME (TRANSITKEY must be set)
+const ActionsList = "encrypt,rewrap,rotate"
+
+func usage() {
+	fmt.Printf("Usage: %s [--profile name] <token> <action>\n", os.Args[0])
+	fmt.Printf("action: %s\n", ActionsList)
+	fmt.Printf("  encrypt: encrypt the credentials of the secrets written before TRANSITKEY was set\n")
+	fmt.Printf("  rewrap: encrypt the credentials again with the latest version of TRANSITKEY\n")
+	fmt.Printf("  rotate: rotate TRANSITKEY then rewrap the credentials\n")
+}
+
+func main() {
+	ctx := context.Background()
+	//the --profile flag select the profile of config.json
+	args := config.ParseFlags()
+	//check if the token and the action are present
+	if len(args) < 2 {
+		fmt.Printf("Error: Missing token and/or action\n")
+		usage()
+		os.Exit(1)
+	}
+	//retreive the token from the first argument
+	token := args[0]
+	action := args[1]
+	//connect to vault using given token
+	secstore, err := securestore.ConnectVaultWithToken(ctx, token)
+	if err != nil {
+		fmt.Printf("Error connecting to vault: %v\n", err)
+		os.Exit(1)
+	}
+	if err := securestore.CheckZeroKnowledge(secstore); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var count int
+	switch action {
+	case "encrypt":
+		count, err = securestore.TransitEncryptExisting(ctx, secstore)
+	case "rewrap":
+		count, err = securestore.TransitRewrap(ctx, secstore)
+	case "rotate":
+		count, err = securestore.TransitRotate(ctx, secstore)
+	default:
+		fmt.Printf("Error: Invalid action\n")
+		usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error: %s %v\n", action, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d entrie(s) written\n", count)
+}
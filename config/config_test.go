@@ -3,17 +3,20 @@ package config
 // Path: config/config_test.go
 // test the config package
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 var CERTIFICATE = "web"
 var MOUNTPATH = "kv"
 
-// test redConfigFile function
-func TestReadConfigFile(t *testing.T) {
+// test readConfig function
+func TestReadConfig(t *testing.T) {
 	//test if config file is not empty
-	if readConfigFile() == nil {
-		t.Errorf("readConfigFile() = %T; want not nil", readConfigFile())
+	if readConfig() == nil {
+		t.Errorf("readConfig() = %T; want not nil", readConfig())
 	}
 }
 
@@ -68,3 +71,52 @@ func TestReadMountPath(t *testing.T) {
 		t.Errorf("ReadMountPath() != %s; want %s", MOUNTPATH, ReadMountPath())
 	}
 }
+
+// test ReadTrashRetention function
+func TestReadTrashRetention(t *testing.T) {
+	//test if TRASHRETENTION is set from the environment
+	t.Setenv("TRASHRETENTION", "48h")
+	if ReadTrashRetention() != 48*time.Hour {
+		t.Errorf("ReadTrashRetention() = %s; want %s", ReadTrashRetention(), 48*time.Hour)
+	}
+	//test if an invalid value fallback to the default
+	t.Setenv("TRASHRETENTION", "forever")
+	if ReadTrashRetention() != TRASHRETENTION {
+		t.Errorf("ReadTrashRetention() = %s; want %s", ReadTrashRetention(), TRASHRETENTION)
+	}
+}
+
+// test the keys of the selected profile replace the top level keys
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"VAULTURL": "https://top:8200", "APPNAME": "top", "PROFILE": "prod",
+		"PROFILES": {"prod": {"VAULTURL": "https://prod:8200"}, "lab": {"VAULTURL": "https://lab:8200", "APPNAME": "lab"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+	t.Setenv("VAULTURL", "")
+	t.Setenv("APPNAME", "")
+	t.Setenv("MYVAULT_PROFILE", "")
+	defer SetProfile("")
+
+	//the PROFILE key select the default profile, missing keys are read from the top level
+	if ReadProfile() != "prod" || ReadVaultURL() != "https://prod:8200" || ReadAPPNAME() != "top" {
+		t.Errorf("prod profile = %s, %s, %s; want prod, https://prod:8200, top", ReadProfile(), ReadVaultURL(), ReadAPPNAME())
+	}
+	t.Setenv("MYVAULT_PROFILE", "lab")
+	if ReadVaultURL() != "https://lab:8200" || ReadAPPNAME() != "lab" {
+		t.Errorf("lab profile = %s, %s; want https://lab:8200, lab", ReadVaultURL(), ReadAPPNAME())
+	}
+	if err := SetProfile("unknown"); err == nil {
+		t.Errorf("SetProfile(unknown) = nil error; want error")
+	}
+	if err := SetProfile("prod"); err != nil || ReadVaultURL() != "https://prod:8200" {
+		t.Errorf("SetProfile(prod) = %v, VAULTURL %s; want https://prod:8200", err, ReadVaultURL())
+	}
+	if profiles := ListProfiles(); len(profiles) != 2 || profiles[0] != "lab" {
+		t.Errorf("ListProfiles() = %v; want [lab prod]", profiles)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 //Configuration file format to be saved as config.json in the same directory as the binary
@@ -12,14 +15,79 @@ import (
 // 	"VAULTURL": "https://xxx.xxx.xxx.xxx:8200"
 // 	"APPNAME": "myapp",
 // 	"CERTIFICATE": "web",
-// 	"MOUNTPATH": "kv"
+// 	"MOUNTPATH": "kv",
+// 	"TRASHRETENTION": "720h",
+// 	"BACKEND": "vault",
+// 	"STOREFILE": "myvault.store",
+// 	"CACHE": "false",
+// 	"CACHEFILE": "myvault.cache",
+// 	"CACHELOCK": "passphrase",
+// 	"NAMESPACE": "",
+// 	"ATTACHMENTMAXSIZE": "10485760",
+// 	"ROTATIONMAXAGE": "",
+// 	"ROTATIONTAGS": {"prod": "90d"},
+// 	"ROTATIONWARNING": "14d",
+// 	"HIBPFILE": "",
+// 	"HIBPMODE": "warn",
+// 	"PASSWORDMINSCORE": "0",
+// 	"PASSWORDPOLICY": "default",
+// 	"PASSWORDPOLICIES": {"web": {"length": 20, "classes": "luds", "minspecial": 2, "noambiguous": true}},
+// 	"VAULTPASSWORDPOLICY": "",
+// 	"TRANSITKEY": "",
+// 	"TRANSITMOUNT": "transit",
+// 	"ZEROKNOWLEDGE": "false",
+// 	"KEYRINGPINFILE": "myvault.keyring"
 // }
 
 var VAULTURL = "https://127.0.0.1:8200"
 var APPNAME = "myapp"
 
-var SecretFieldNames = []string{"Username", "Credential", "URL", "LastUpdate", "LastUpdateBy", "Comment"}
-var SecretHumanFieldNames = []string{"Username", "Credential", "URL", "Comment"}
+// deleted secrets are kept in the trash for 30 days by default
+var TRASHRETENTION = 720 * time.Hour
+
+var BACKEND = "vault"
+var STOREFILE = "myvault.store"
+
+// the offline cache is disabled by default
+var CACHE = false
+var CACHEFILE = "myvault.cache"
+var CACHELOCK = "passphrase"
+
+// the vault enterprise namespace, empty is the root namespace
+var NAMESPACE = ""
+
+// the largest file that can be attached to a secret (in bytes)
+var ATTACHMENTMAXSIZE int64 = 10 * 1024 * 1024
+
+// the secrets have no rotation period by default, they are reported 14 days before they are due
+var ROTATIONMAXAGE = ""
+var ROTATIONWARNING = "14d"
+
+// the breached passwords check is disabled until a Have I Been Pwned file is set, a breached credential is only reported
+var HIBPFILE = ""
+var HIBPMODE = "warn"
+
+// the lowest strength score (0 very weak to 4 strong) accepted for a credential typed by the user
+var PASSWORDMINSCORE = 0
+
+// the policy of the generated credentials, default is the builtin 16 characters policy
+var PASSWORDPOLICY = "default"
+
+// the vault password policy (sys/policies/password) of the generated credentials, empty to use the local generator
+var VAULTPASSWORDPOLICY = ""
+
+// the credentials are stored as is in KV unless a transit key is set
+var TRANSITKEY = ""
+var TRANSITMOUNT = "transit"
+
+// the credentials are not encrypted with the data key of the user by default
+var ZEROKNOWLEDGE = false
+
+// the fingerprints of the zero knowledge data keys trusted on this computer
+var KEYRINGPINFILE = "myvault.keyring"
+
+var SecretFieldNames = []string{"Username", "Credential", "URL", "LastUpdate", "LastUpdateBy", "Comment", "Tags", "OTP", "MaxAge"}
+var SecretHumanFieldNames = []string{"Username", "Credential", "URL", "Comment", "Tags", "OTP", "MaxAge"}
 
 // User is the user running the application
 var User = func() string {
@@ -36,44 +104,67 @@ func Usage() {
 	// 	"VAULTURL": "https://xxx.xxx.xxx.xxx:8200"
 	// 	"APPNAME": "myapp",
 	// 	"CERTIFICATE": "web",
-	// 	"MOUNTPATH": "kv"
+	// 	"MOUNTPATH": "kv",
+	// 	"TRASHRETENTION": "720h",
+	// 	"BACKEND": "vault",
+	// 	"STOREFILE": "myvault.store",
+	// 	"CACHE": "false",
+	// 	"CACHEFILE": "myvault.cache",
+	// 	"CACHELOCK": "passphrase",
+	// 	"NAMESPACE": "",
+	// 	"ATTACHMENTMAXSIZE": "10485760",
+	// 	"ROTATIONMAXAGE": "",
+	// 	"ROTATIONTAGS": {"prod": "90d"},
+	// 	"ROTATIONWARNING": "14d",
+	// 	"HIBPFILE": "",
+	// 	"HIBPMODE": "warn",
+	// 	"PASSWORDMINSCORE": "0",
+	// 	"PASSWORDPOLICY": "default",
+	// 	"PASSWORDPOLICIES": {"web": {"length": 20, "classes": "luds", "minspecial": 2, "noambiguous": true}},
+	// 	"VAULTPASSWORDPOLICY": "",
+	// 	"TRANSITKEY": "",
+	// 	"TRANSITMOUNT": "transit",
+	// 	"ZEROKNOWLEDGE": "false",
+	// 	"KEYRINGPINFILE": "myvault.keyring"
 	// }
 	fmt.Printf("Configuration file format to be saved as config.json in the same directory where the binary is run from\n")
 	fmt.Printf("{\n")
 	fmt.Printf("\t\"VAULTURL\": \"https://xxx.xxx.xxx.xxx:8200\"\n")
 	fmt.Printf("\t\"APPNAME\": \"myapp\",\n")
 	fmt.Printf("\t\"CERTIFICATE\": \"web\",\n")
-	fmt.Printf("\t\"MOUNTPATH\": \"kv\"\n")
+	fmt.Printf("\t\"MOUNTPATH\": \"kv\",\n")
+	fmt.Printf("\t\"TRASHRETENTION\": \"720h\",\n")
+	fmt.Printf("\t\"BACKEND\": \"vault\", (vault, file or memory)\n")
+	fmt.Printf("\t\"STOREFILE\": \"myvault.store\", (encrypted file used by the file backend)\n")
+	fmt.Printf("\t\"CACHE\": \"false\", (keep an encrypted offline copy of the secrets)\n")
+	fmt.Printf("\t\"CACHEFILE\": \"myvault.cache\",\n")
+	fmt.Printf("\t\"CACHELOCK\": \"passphrase\", (passphrase or yubikey)\n")
+	fmt.Printf("\t\"NAMESPACE\": \"\", (vault enterprise namespace, empty for the root namespace)\n")
+	fmt.Printf("\t\"ATTACHMENTMAXSIZE\": \"10485760\", (largest attachment in bytes)\n")
+	fmt.Printf("\t\"ROTATIONMAXAGE\": \"\", (default rotation period of the secrets, 90d or 2160h, empty for none)\n")
+	fmt.Printf("\t\"ROTATIONTAGS\": {\"prod\": \"90d\"}, (rotation period of the secrets carrying a tag)\n")
+	fmt.Printf("\t\"ROTATIONWARNING\": \"14d\", (secrets due within this period are reported)\n")
+	fmt.Printf("\t\"HIBPFILE\": \"\", (Have I Been Pwned SHA-1 file ordered by hash, empty to disable the check)\n")
+	fmt.Printf("\t\"HIBPMODE\": \"warn\", (warn or refuse a breached credential)\n")
+	fmt.Printf("\t\"PASSWORDMINSCORE\": \"0\", (lowest strength accepted for a typed credential, 0 very weak to 4 strong)\n")
+	fmt.Printf("\t\"PASSWORDPOLICY\": \"default\", (policy of the generated credentials)\n")
+	fmt.Printf("\t\"PASSWORDPOLICIES\": {\"web\": {\"length\": 20, \"classes\": \"luds\", \"minspecial\": 2, \"noambiguous\": true}}, (named password policies)\n")
+	fmt.Printf("\t\"VAULTPASSWORDPOLICY\": \"\", (vault password policy of the generated credentials, or {\"myapp\": \"policy\"} per APPNAME)\n")
+	fmt.Printf("\t\"TRANSITKEY\": \"\", (transit key encrypting the credentials before they are written, empty to disable)\n")
+	fmt.Printf("\t\"TRANSITMOUNT\": \"transit\", (mount of the transit engine)\n")
+	fmt.Printf("\t\"ZEROKNOWLEDGE\": \"false\", (encrypt the credentials with a data key wrapped to the Yubikey key management slot)\n")
+	fmt.Printf("\t\"KEYRINGPINFILE\": \"myvault.keyring\" (fingerprints of the zero knowledge data keys trusted on this computer)\n")
 	fmt.Printf("}\n")
 
 }
 
-// read configuration file and return the configuration
-func readConfigFile() *json.Decoder {
-	//read the configuration file
-	// Open the file
-	configfile, err := os.Open("config.json")
-	if err != nil {
-		Usage()
-		log.Fatal(err)
-	}
-	// Parse the json file
-	r := json.NewDecoder(configfile)
-
-	return r
-
-}
-
 // read VAULTURL from environment variable, configuration file or use default
 func ReadVaultURL() string {
 	if os.Getenv("VAULTURL") != "" {
 		return os.Getenv("VAULTURL")
 	}
-	configfile := readConfigFile()
-	var config map[string]interface{}
-	configfile.Decode(&config)
-	if config["VAULTURL"] != nil {
-		return config["VAULTURL"].(string)
+	if value, ok := readConfigValue("VAULTURL").(string); ok {
+		return value
 	}
 	return VAULTURL
 }
@@ -83,11 +174,8 @@ func ReadAPPNAME() string {
 	if os.Getenv("APPNAME") != "" {
 		return os.Getenv("APPNAME")
 	}
-	configfile := readConfigFile()
-	var config map[string]interface{}
-	configfile.Decode(&config)
-	if config["APPNAME"] != nil {
-		return config["APPNAME"].(string)
+	if value, ok := readConfigValue("APPNAME").(string); ok {
+		return value
 	}
 	return APPNAME
 }
@@ -97,11 +185,8 @@ func ReadCertificateName() string {
 	if os.Getenv("CERTIFICATE") != "" {
 		return os.Getenv("CERTIFICATE")
 	}
-	configfile := readConfigFile()
-	var config map[string]interface{}
-	configfile.Decode(&config)
-	if config["CERTIFICATE"] != nil {
-		return config["CERTIFICATE"].(string)
+	if value, ok := readConfigValue("CERTIFICATE").(string); ok {
+		return value
 	}
 	return "web"
 }
@@ -111,11 +196,318 @@ func ReadMountPath() string {
 	if os.Getenv("MOUNTPATH") != "" {
 		return os.Getenv("MOUNTPATH")
 	}
-	configfile := readConfigFile()
-	var config map[string]interface{}
-	configfile.Decode(&config)
-	if config["MOUNTPATH"] != nil {
-		return config["MOUNTPATH"].(string)
+	if value, ok := readConfigValue("MOUNTPATH").(string); ok {
+		return value
 	}
 	return "kv"
 }
+
+// read the trash retention period from environment variable, configuration file or use default
+// the value is a duration such as "720h"
+func ReadTrashRetention() time.Duration {
+	value := os.Getenv("TRASHRETENTION")
+	if value == "" {
+		value, _ = readConfigValue("TRASHRETENTION").(string)
+	}
+	if value == "" {
+		return TRASHRETENTION
+	}
+	retention, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid TRASHRETENTION %s, using %s\n", value, TRASHRETENTION)
+		return TRASHRETENTION
+	}
+	return retention
+}
+
+// read the storage backend (vault, file or memory) from environment variable, configuration file or use default
+func ReadBackend() string {
+	if os.Getenv("BACKEND") != "" {
+		return os.Getenv("BACKEND")
+	}
+	if value, ok := readConfigValue("BACKEND").(string); ok {
+		return value
+	}
+	return BACKEND
+}
+
+// read the file used by the file backend from environment variable, configuration file or use default
+func ReadStoreFile() string {
+	if os.Getenv("STOREFILE") != "" {
+		return os.Getenv("STOREFILE")
+	}
+	if value, ok := readConfigValue("STOREFILE").(string); ok {
+		return value
+	}
+	return STOREFILE
+}
+
+// read if the offline cache is enabled from environment variable, configuration file or use default
+func ReadCache() bool {
+	value := os.Getenv("CACHE")
+	if value == "" {
+		switch v := readConfigValue("CACHE").(type) {
+		case bool:
+			return v
+		case string:
+			value = v
+		}
+	}
+	if value == "" {
+		return CACHE
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid CACHE %s, using %t\n", value, CACHE)
+		return CACHE
+	}
+	return enabled
+}
+
+// read the offline cache file from environment variable, configuration file or use default
+func ReadCacheFile() string {
+	if os.Getenv("CACHEFILE") != "" {
+		return os.Getenv("CACHEFILE")
+	}
+	if value, ok := readConfigValue("CACHEFILE").(string); ok {
+		return value
+	}
+	// each profile keep its own offline cache
+	if ReadProfile() != "" {
+		return strings.TrimSuffix(CACHEFILE, ".cache") + "-" + ReadProfile() + ".cache"
+	}
+	return CACHEFILE
+}
+
+// read how the offline cache is locked (passphrase or yubikey) from environment variable, configuration file or use default
+func ReadCacheLock() string {
+	if os.Getenv("CACHELOCK") != "" {
+		return os.Getenv("CACHELOCK")
+	}
+	if value, ok := readConfigValue("CACHELOCK").(string); ok {
+		return value
+	}
+	return CACHELOCK
+}
+
+// read the vault enterprise namespace from environment variable, configuration file or use default
+// VAULT_NAMESPACE is used when NAMESPACE is not set
+func ReadNamespace() string {
+	if os.Getenv("NAMESPACE") != "" {
+		return os.Getenv("NAMESPACE")
+	}
+	if os.Getenv("VAULT_NAMESPACE") != "" {
+		return os.Getenv("VAULT_NAMESPACE")
+	}
+	if value, ok := readConfigValue("NAMESPACE").(string); ok {
+		return value
+	}
+	return NAMESPACE
+}
+
+// read the largest attachment size (in bytes) from environment variable, configuration file or use default
+func ReadAttachmentMaxSize() int64 {
+	value := os.Getenv("ATTACHMENTMAXSIZE")
+	if value == "" {
+		switch v := readConfigValue("ATTACHMENTMAXSIZE").(type) {
+		case float64:
+			return int64(v)
+		case string:
+			value = v
+		}
+	}
+	if value == "" {
+		return ATTACHMENTMAXSIZE
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 {
+		log.Printf("Invalid ATTACHMENTMAXSIZE %s, using %d\n", value, ATTACHMENTMAXSIZE)
+		return ATTACHMENTMAXSIZE
+	}
+	return size
+}
+
+// read the default rotation period of the secrets from environment variable, configuration file or use default
+func ReadRotationMaxAge() string {
+	if os.Getenv("ROTATIONMAXAGE") != "" {
+		return os.Getenv("ROTATIONMAXAGE")
+	}
+	if value, ok := readConfigValue("ROTATIONMAXAGE").(string); ok {
+		return value
+	}
+	return ROTATIONMAXAGE
+}
+
+// read the rotation period of the tags from environment variable ("prod=90d,db=30d") or configuration file
+func ReadRotationTags() map[string]string {
+	rValue := make(map[string]string)
+	if value := os.Getenv("ROTATIONTAGS"); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			if tag, age, found := strings.Cut(item, "="); found {
+				rValue[strings.ToLower(strings.TrimSpace(tag))] = strings.TrimSpace(age)
+			}
+		}
+		return rValue
+	}
+	if tags, ok := readConfigValue("ROTATIONTAGS").(map[string]interface{}); ok {
+		for tag, age := range tags {
+			if value, isString := age.(string); isString {
+				rValue[strings.ToLower(tag)] = value
+			}
+		}
+	}
+	return rValue
+}
+
+// read how long before their due date the secrets are reported from environment variable, configuration file or use default
+func ReadRotationWarning() string {
+	if os.Getenv("ROTATIONWARNING") != "" {
+		return os.Getenv("ROTATIONWARNING")
+	}
+	if value, ok := readConfigValue("ROTATIONWARNING").(string); ok {
+		return value
+	}
+	return ROTATIONWARNING
+}
+
+// read the Have I Been Pwned file from environment variable, configuration file or use default
+func ReadHIBPFile() string {
+	if os.Getenv("HIBPFILE") != "" {
+		return os.Getenv("HIBPFILE")
+	}
+	if value, ok := readConfigValue("HIBPFILE").(string); ok {
+		return value
+	}
+	return HIBPFILE
+}
+
+// read what to do with a breached credential (warn or refuse) from environment variable, configuration file or use default
+func ReadHIBPMode() string {
+	if os.Getenv("HIBPMODE") != "" {
+		return os.Getenv("HIBPMODE")
+	}
+	if value, ok := readConfigValue("HIBPMODE").(string); ok {
+		return value
+	}
+	return HIBPMODE
+}
+
+// read the lowest strength score accepted for a typed credential from environment variable, configuration file or use default
+func ReadPasswordMinScore() int {
+	value := os.Getenv("PASSWORDMINSCORE")
+	if value == "" {
+		switch v := readConfigValue("PASSWORDMINSCORE").(type) {
+		case float64:
+			return int(v)
+		case string:
+			value = v
+		}
+	}
+	if value == "" {
+		return PASSWORDMINSCORE
+	}
+	score, err := strconv.Atoi(value)
+	if err != nil || score < 0 || score > 4 {
+		log.Printf("Invalid PASSWORDMINSCORE %s, using %d\n", value, PASSWORDMINSCORE)
+		return PASSWORDMINSCORE
+	}
+	return score
+}
+
+// read the name of the policy of the generated credentials from environment variable, configuration file or use default
+func ReadPasswordPolicy() string {
+	if os.Getenv("PASSWORDPOLICY") != "" {
+		return os.Getenv("PASSWORDPOLICY")
+	}
+	if value, ok := readConfigValue("PASSWORDPOLICY").(string); ok {
+		return value
+	}
+	return PASSWORDPOLICY
+}
+
+// read the named password policies from environment variable (a JSON object) or configuration file
+func ReadPasswordPolicies() map[string]interface{} {
+	rValue := make(map[string]interface{})
+	if value := os.Getenv("PASSWORDPOLICIES"); value != "" {
+		if err := json.Unmarshal([]byte(value), &rValue); err != nil {
+			log.Printf("Invalid PASSWORDPOLICIES: %v\n", err)
+		}
+		return rValue
+	}
+	if policies, ok := readConfigValue("PASSWORDPOLICIES").(map[string]interface{}); ok {
+		return policies
+	}
+	return rValue
+}
+
+// read the vault password policy of the generated credentials from environment variable, configuration file or use default
+// the configuration value is a policy name or an object giving the policy of each APPNAME
+func ReadVaultPasswordPolicy() string {
+	if os.Getenv("VAULTPASSWORDPOLICY") != "" {
+		return os.Getenv("VAULTPASSWORDPOLICY")
+	}
+	switch v := readConfigValue("VAULTPASSWORDPOLICY").(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if value, ok := v[ReadAPPNAME()].(string); ok {
+			return value
+		}
+	}
+	return VAULTPASSWORDPOLICY
+}
+
+// read the transit key encrypting the sensitive fields from environment variable, configuration file or use default
+func ReadTransitKey() string {
+	if os.Getenv("TRANSITKEY") != "" {
+		return os.Getenv("TRANSITKEY")
+	}
+	if value, ok := readConfigValue("TRANSITKEY").(string); ok {
+		return value
+	}
+	return TRANSITKEY
+}
+
+// read the mount of the transit engine from environment variable, configuration file or use default
+func ReadTransitMount() string {
+	if os.Getenv("TRANSITMOUNT") != "" {
+		return os.Getenv("TRANSITMOUNT")
+	}
+	if value, ok := readConfigValue("TRANSITMOUNT").(string); ok {
+		return value
+	}
+	return TRANSITMOUNT
+}
+
+// read if the secrets are encrypted with the zero knowledge data key from environment variable, configuration file or use default
+func ReadZeroKnowledge() bool {
+	value := os.Getenv("ZEROKNOWLEDGE")
+	if value == "" {
+		switch v := readConfigValue("ZEROKNOWLEDGE").(type) {
+		case bool:
+			return v
+		case string:
+			value = v
+		}
+	}
+	if value == "" {
+		return ZEROKNOWLEDGE
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid ZEROKNOWLEDGE %s, using %t\n", value, ZEROKNOWLEDGE)
+		return ZEROKNOWLEDGE
+	}
+	return enabled
+}
+
+// read the file of the pinned zero knowledge data keys from environment variable, configuration file or use default
+func ReadKeyringPinFile() string {
+	if os.Getenv("KEYRINGPINFILE") != "" {
+		return os.Getenv("KEYRINGPINFILE")
+	}
+	if value, ok := readConfigValue("KEYRINGPINFILE").(string); ok {
+		return value
+	}
+	return KEYRINGPINFILE
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+// Profiles allow one configuration file to describe several vault servers
+// the keys of the selected profile replace the top level keys, the missing ones are read from the top level
+// {
+// 	"APPNAME": "myapp",
+// 	"PROFILE": "prod",
+// 	"PROFILES": {
+// 		"prod": {"VAULTURL": "https://vault.prod:8200", "MOUNTPATH": "kv", "CERTIFICATE": "web"},
+// 		"lab": {"VAULTURL": "https://vault.lab:8200", "MOUNTPATH": "secret", "CERTIFICATE": "lab"}
+// 	}
+// }
+
+// the profile selected with SetProfile, it wins over MYVAULT_PROFILE and the PROFILE key
+var profile string
+
+// read the configuration file and return its content
+func readConfig() map[string]interface{} {
+	configfile, err := os.Open("config.json")
+	if err != nil {
+		Usage()
+		log.Fatal(err)
+	}
+	defer configfile.Close()
+	var config map[string]interface{}
+	json.NewDecoder(configfile).Decode(&config)
+	return config
+}
+
+// return the profiles of the configuration file
+func readProfiles(config map[string]interface{}) map[string]interface{} {
+	profiles, _ := config["PROFILES"].(map[string]interface{})
+	return profiles
+}
+
+// return the value of key from the selected profile or from the top level of the configuration file
+// nil is returned when the key is not set
+func readConfigValue(key string) interface{} {
+	config := readConfig()
+	name := selectedProfile(config)
+	if name == "" {
+		return config[key]
+	}
+	values, ok := readProfiles(config)[name].(map[string]interface{})
+	if !ok {
+		log.Fatalf("Profile %s not found in config.json\n", name)
+	}
+	if values[key] != nil {
+		return values[key]
+	}
+	return config[key]
+}
+
+// return the selected profile: SetProfile, MYVAULT_PROFILE or the PROFILE key
+func selectedProfile(config map[string]interface{}) string {
+	if profile != "" {
+		return profile
+	}
+	if os.Getenv("MYVAULT_PROFILE") != "" {
+		return os.Getenv("MYVAULT_PROFILE")
+	}
+	name, _ := config["PROFILE"].(string)
+	return name
+}
+
+// return the selected profile, empty when no profile is used
+func ReadProfile() string {
+	return selectedProfile(readConfig())
+}
+
+// return the names of the profiles of the configuration file
+func ListProfiles() []string {
+	var rValue []string
+	for name := range readProfiles(readConfig()) {
+		rValue = append(rValue, name)
+	}
+	sort.Strings(rValue)
+	return rValue
+}
+
+// select the profile used by the Read functions, an empty name go back to MYVAULT_PROFILE or the PROFILE key
+func SetProfile(name string) error {
+	if name != "" {
+		if _, ok := readProfiles(readConfig())[name]; !ok {
+			return fmt.Errorf("profile %s not found in config.json", name)
+		}
+	}
+	profile = name
+	return nil
+}
+
+// parse the command line flags shared by the binaries (--profile) and return the remaining arguments
+func ParseFlags() []string {
+	name := flag.String("profile", "", "profile of config.json to use (default MYVAULT_PROFILE or the PROFILE key)")
+	flag.Parse()
+	if err := SetProfile(*name); err != nil {
+		log.Fatal(err)
+	}
+	return flag.Args()
+}
@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BreachList is a local copy of the Have I Been Pwned SHA-1 passwords, one "HASH:COUNT" line per
+// password sorted by hash (the "ordered by hash" download), it is binary searched without loading it
+type BreachList struct {
+	file *os.File
+	size int64
+}
+
+// this function will open the breach list file
+func OpenBreachList(path string) (*BreachList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &BreachList{file: f, size: info.Size()}, nil
+}
+
+// close the breach list file
+func (b *BreachList) Close() error {
+	return b.file.Close()
+}
+
+// return the first line starting at or after the offset and its start, the line is empty at the end of the file
+func (b *BreachList) lineAt(offset int64) (int64, string, error) {
+	start := offset
+	r := bufio.NewReader(io.NewSectionReader(b.file, offset, b.size-offset))
+	if offset > 0 {
+		//skip the end of the line containing offset-1
+		r = bufio.NewReader(io.NewSectionReader(b.file, offset-1, b.size-offset+1))
+		skipped, err := r.ReadString('\n')
+		if err == io.EOF {
+			return b.size, "", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		start = offset - 1 + int64(len(skipped))
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	return start, line, nil
+}
+
+// this function will return how many times the password was seen in breaches, 0 if it is not in the list
+func (b *BreachList) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := b.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi || line == "" {
+			hi = mid
+			continue
+		}
+		lineHash, count, _ := strings.Cut(strings.TrimSpace(line), ":")
+		switch strings.Compare(strings.ToUpper(lineHash), hash) {
+		case 0:
+			n, err := strconv.Atoi(count)
+			if err != nil {
+				return 0, fmt.Errorf("breach list line %q is not valid", strings.TrimSpace(line))
+			}
+			return n, nil
+		case -1:
+			lo = start + int64(len(line))
+		default:
+			hi = start
+		}
+	}
+	return 0, nil
+}
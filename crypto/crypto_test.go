@@ -1,8 +1,23 @@
 package crypto
 
 import (
+	"bytes"
+	gocrypto "crypto"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 //this will allow to test the function
@@ -76,3 +91,287 @@ func TestRandomPassword(t *testing.T) {
 		t.Errorf("RandomPassword() = >%s<; want 12 spaces", RandomPassword(12, false, false, false, false, SpecialList))
 	}
 }
+
+// test the pbkdf2 function with the RFC 7914 PBKDF2-HMAC-SHA256 test vectors
+func TestPbkdf2(t *testing.T) {
+	var testcases = []struct {
+		password, salt string
+		iterations     int
+		keyLen         int
+		expected       string
+	}{
+		{"passwd", "salt", 1, 64, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, 64, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, tc := range testcases {
+		if got := hex.EncodeToString(pbkdf2([]byte(tc.password), []byte(tc.salt), tc.iterations, tc.keyLen)); got != tc.expected {
+			t.Errorf("pbkdf2(%s, %s) = %s; want %s", tc.password, tc.salt, got, tc.expected)
+		}
+	}
+}
+
+// test the Seal and Open functions
+func TestSealOpen(t *testing.T) {
+	salt, _ := NewSalt()
+	key := DeriveKey("passphrase", salt)
+	sealed, err := Seal(key, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Seal() error %v", err)
+	}
+	if plain, err := Open(key, sealed); err != nil || string(plain) != "secret" {
+		t.Errorf("Open() = %s, %v; want secret", plain, err)
+	}
+	if _, err := Open(DeriveKey("wrong", salt), sealed); err == nil {
+		t.Errorf("Open() with wrong key = nil error; want error")
+	}
+}
+
+// test WrapKey and UnwrapKey with EC and RSA software keys
+func TestWrapKey(t *testing.T) {
+	dataKey, _ := NewDataKey()
+	ecKey, _ := NewSoftwareKey()
+	rsaKey, _ := rsa.GenerateKey(cryptorand.Reader, 2048)
+	var testcases = []struct {
+		name string
+		pub  gocrypto.PublicKey
+		priv gocrypto.PrivateKey
+	}{
+		{"ecdh", &ecKey.PublicKey, ecKey},
+		{"rsa", &rsaKey.PublicKey, rsaKey},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			wrapped, err := WrapKey(tc.pub, dataKey)
+			if err != nil {
+				t.Fatalf("WrapKey() error %v", err)
+			}
+			if key, err := UnwrapKey(tc.priv, wrapped); err != nil || !bytes.Equal(key, dataKey) {
+				t.Errorf("UnwrapKey() = %x, %v; want %x", key, err, dataKey)
+			}
+		})
+	}
+}
+
+// test the TOTP codes with the test vectors of RFC 6238 (appendix B)
+func TestTOTP(t *testing.T) {
+	keys := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	var testcases = []struct {
+		time      int64
+		algorithm string
+		code      string
+	}{
+		{59, "SHA1", "94287082"}, {59, "SHA256", "46119246"}, {59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"}, {1111111109, "SHA256", "68084774"}, {1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"}, {1111111111, "SHA256", "67062674"}, {1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"}, {1234567890, "SHA256", "91819424"}, {1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"}, {2000000000, "SHA256", "90698825"}, {2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"}, {20000000000, "SHA256", "77737706"}, {20000000000, "SHA512", "47863826"},
+	}
+	for _, tc := range testcases {
+		totp := TOTP{Secret: base32.StdEncoding.EncodeToString([]byte(keys[tc.algorithm])), Digits: 8, Period: 30, Algorithm: tc.algorithm}
+		if code, err := totp.Code(time.Unix(tc.time, 0)); err != nil || code != tc.code {
+			t.Errorf("Code(%d, %s) = %s, %v; want %s", tc.time, tc.algorithm, code, err, tc.code)
+		}
+	}
+	totp, err := ParseTOTP("otpauth://totp/ACME:alice@example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=ACME&digits=8")
+	if err != nil || totp.Issuer != "ACME" || totp.Account != "alice@example.com" || totp.Digits != 8 || totp.Period != TOTPPeriod || totp.Algorithm != "SHA1" {
+		t.Fatalf("ParseTOTP() = %+v, %v", totp, err)
+	}
+	if code, _ := totp.Code(time.Unix(59, 0)); code != "94287082" {
+		t.Errorf("Code() of the parsed URI = %s; want 94287082", code)
+	}
+	if again, err := ParseTOTP(totp.URI()); err != nil || again != totp {
+		t.Errorf("ParseTOTP(URI()) = %+v, %v; want %+v", again, err, totp)
+	}
+	if remaining := totp.Remaining(time.Unix(59, 0)); remaining != 1 {
+		t.Errorf("Remaining() = %d; want 1", remaining)
+	}
+	for _, invalid := range []string{"", "not base32!", "otpauth://hotp/x?secret=GEZDGNBV", "otpauth://totp/x?secret=GEZDGNBV&algorithm=MD5"} {
+		if _, err := ParseTOTP(invalid); err == nil {
+			t.Errorf("ParseTOTP(%q) = nil error; want error", invalid)
+		}
+	}
+}
+
+// test the binary search of the breach list
+func TestBreachList(t *testing.T) {
+	passwords := []string{"password", "123456", "qwerty", "letmein", "dragon"}
+	var lines []string
+	for i, p := range passwords {
+		sum := sha1.Sum([]byte(p))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0600)
+	list, err := OpenBreachList(path)
+	if err != nil {
+		t.Fatalf("OpenBreachList() error %v", err)
+	}
+	defer list.Close()
+	for i, p := range passwords {
+		if count, err := list.Count(p); err != nil || count != i+1 {
+			t.Errorf("Count(%s) = %d, %v; want %d", p, count, err, i+1)
+		}
+	}
+	for _, p := range []string{"", "not-breached-9f2c", "zzzzzz"} {
+		if count, err := list.Count(p); err != nil || count != 0 {
+			t.Errorf("Count(%s) = %d, %v; want 0", p, count, err)
+		}
+	}
+}
+
+// test the strength estimation of common patterns
+func TestEstimateStrength(t *testing.T) {
+	var testcases = []struct {
+		password string
+		maxScore int
+		minScore int
+		feedback string
+	}{
+		{"password", StrengthVeryWeak, StrengthVeryWeak, "common passwords"},
+		{"P@ssw0rd", StrengthVeryWeak, StrengthVeryWeak, "substitutions"},
+		{"qwertyuiop", StrengthWeak, StrengthVeryWeak, "keyboard"},
+		{"abcdefgh", StrengthVeryWeak, StrengthVeryWeak, "sequences"},
+		{"aaaaaaaaaaaa", StrengthVeryWeak, StrengthVeryWeak, "repeats"},
+		{"12/05/1987", StrengthWeak, StrengthVeryWeak, "dates"},
+		{"alice2", StrengthWeak, StrengthVeryWeak, "username"},
+		{"x7#Kp9!qLm2$vB", StrengthStrong, StrengthStrong, ""},
+	}
+	for _, tc := range testcases {
+		s := EstimateStrength(tc.password, "alice@example.com")
+		if s.Score > tc.maxScore || s.Score < tc.minScore {
+			t.Errorf("EstimateStrength(%s) score = %d; want %d to %d", tc.password, s.Score, tc.minScore, tc.maxScore)
+		}
+		if tc.feedback != "" && !strings.Contains(strings.Join(s.Feedback, " "), tc.feedback) {
+			t.Errorf("EstimateStrength(%s) feedback = %v; want %s", tc.password, s.Feedback, tc.feedback)
+		}
+		if tc.feedback == "" && len(s.Feedback) != 0 {
+			t.Errorf("EstimateStrength(%s) feedback = %v; want none", tc.password, s.Feedback)
+		}
+	}
+}
+
+// test the strength estimation of long l33t and repeated passwords stays fast
+func TestEstimateStrengthTime(t *testing.T) {
+	for _, password := range []string{strings.Repeat("1", 64), strings.Repeat("1|", 32), strings.Repeat("a", 128), strings.Repeat("P@ssw0rd", 16)} {
+		start := time.Now()
+		EstimateStrength(password, "alice@example.com")
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("EstimateStrength(%s) took %v; want less than 1s", password, elapsed)
+		}
+	}
+}
+
+// test the diceware passphrase generator
+func TestRandomPassphrase(t *testing.T) {
+	if len(Wordlist) != 1296 {
+		t.Fatalf("Wordlist has %d words; want 1296", len(Wordlist))
+	}
+	passphrase, entropy, err := RandomPassphrase(PassphraseOptions{Words: 5, Separator: "-", Capitalize: true, Digit: true})
+	if err != nil {
+		t.Fatalf("RandomPassphrase() error %v", err)
+	}
+	words := strings.Split(passphrase, "-")
+	if len(words) != 5 || !Checkpassword(passphrase, true, true, true, false, "") {
+		t.Errorf("RandomPassphrase() = %s; want 5 capitalized words and a digit", passphrase)
+	}
+	if math.Abs(entropy-(5*math.Log2(1296)+math.Log2(50))) > 0.01 {
+		t.Errorf("RandomPassphrase() entropy = %.2f; want %.2f", entropy, 5*math.Log2(1296)+math.Log2(50))
+	}
+	if passphrase, entropy, _ := RandomPassphrase(PassphraseOptions{}); len(strings.Fields(passphrase)) != 1 || entropy < 62 {
+		t.Errorf("RandomPassphrase() default = %s, %.2f bits; want 6 joined words and 62 bits", passphrase, entropy)
+	}
+}
+
+// test the password policies
+func TestGeneratePassword(t *testing.T) {
+	count := func(password, list string) int {
+		n := 0
+		for _, c := range password {
+			if strings.ContainsRune(list, c) {
+				n++
+			}
+		}
+		return n
+	}
+	policy := PasswordPolicy{Length: 30, Classes: "luds", MinUpper: 3, MinDigit: 4, MinSpecial: 5, ExcludeAmbiguous: true}
+	for i := 0; i < 50; i++ {
+		pwd, err := GeneratePassword(policy)
+		if err != nil {
+			t.Fatalf("GeneratePassword() error %v", err)
+		}
+		if len(pwd) != 30 || count(pwd, UpperList) < 3 || count(pwd, DigitList) < 4 || count(pwd, SpecialList) < 5 {
+			t.Errorf("GeneratePassword() = %s; want 30 characters with 3 uppercase, 4 digits and 5 specials", pwd)
+		}
+		if strings.ContainsAny(pwd, AmbiguousList) {
+			t.Errorf("GeneratePassword() = %s; want no ambiguous character", pwd)
+		}
+	}
+	if _, err := GeneratePassword(PasswordPolicy{Length: 3, MinLower: 2, MinDigit: 2}); err == nil {
+		t.Errorf("GeneratePassword() want an error when the length is shorter than the minimum counts")
+	}
+	if _, err := GeneratePassword(PasswordPolicy{Length: 10}); err == nil {
+		t.Errorf("GeneratePassword() want an error without character class")
+	}
+	if password, err := GeneratePassword(PasswordPolicy{Classes: "lud"}); err != nil || len(password) != DefaultPolicy.Length {
+		t.Errorf("GeneratePassword() without length = %q, %v; want %d characters", password, err, DefaultPolicy.Length)
+	}
+	if _, err := GeneratePassword(PasswordPolicy{Pattern: "\\"}); err == nil {
+		t.Errorf("GeneratePassword() want an error when the pattern gives an empty password")
+	}
+	//pattern mode
+	pattern := regexp.MustCompile(`^[BCDFGHJKLMNPQRSTVWXZ][aeiouy][bcdfghjklmnpqrstvwxz]{2}-[0-9]{4}\*$`)
+	for i := 0; i < 20; i++ {
+		if pwd, err := GeneratePassword(PasswordPolicy{Pattern: `Cvcc-9999\*`}); err != nil || !pattern.MatchString(pwd) {
+			t.Errorf("GeneratePassword() = %s, %v; want the pattern Cvcc-9999*", pwd, err)
+		}
+	}
+	//pronounceable mode
+	pronounceable := regexp.MustCompile(`^([bcdfghjklmnpqrstvwxzBCDFGHJKLMNPQRSTVWXZ][aeiouyAEIOUY])+[0-9]{2}$`)
+	for i := 0; i < 20; i++ {
+		pwd, err := GeneratePassword(BuiltinPolicies["pronounceable"])
+		if err != nil || len(pwd) != 14 || count(pwd, UpperList) != 1 || !pronounceable.MatchString(pwd) {
+			t.Errorf("GeneratePassword() = %s, %v; want 12 pronounceable letters with 1 uppercase and 2 digits", pwd, err)
+		}
+	}
+	//configured policies replace the builtin ones
+	policies := map[string]interface{}{"pin": map[string]interface{}{"pattern": "9999"}, "web": map[string]interface{}{"length": 20.0, "classes": "lu", "noambiguous": true}}
+	if p, err := LookupPolicy("pin", policies); err != nil || p.Pattern != "9999" {
+		t.Errorf("LookupPolicy(pin) = %+v, %v; want the configured pattern 9999", p, err)
+	}
+	if p, err := LookupPolicy("web", policies); err != nil || p.Length != 20 || p.Classes != "lu" || !p.ExcludeAmbiguous {
+		t.Errorf("LookupPolicy(web) = %+v, %v; want the configured policy", p, err)
+	}
+	if p, err := LookupPolicy("", nil); err != nil || p != DefaultPolicy {
+		t.Errorf("LookupPolicy() = %+v, %v; want the default policy", p, err)
+	}
+	if _, err := LookupPolicy("unknown", policies); err == nil {
+		t.Errorf("LookupPolicy(unknown) want an error")
+	}
+	if names := PolicyNames(policies); strings.Join(names, ",") != "alnum,default,pin,pronounceable,web" {
+		t.Errorf("PolicyNames() = %v", names)
+	}
+}
+
+// test the recovery codes
+func TestRecoveryCode(t *testing.T) {
+	code, err := NewRecoveryCode()
+	if err != nil {
+		t.Fatalf("NewRecoveryCode() error %v", err)
+	}
+	if !regexp.MustCompile(`^[A-Z2-7]{4}(-[A-Z2-7]{4}){7}$`).MatchString(code) {
+		t.Errorf("NewRecoveryCode() = %s; want 8 groups of 4 base32 characters", code)
+	}
+	normalized, err := NormalizeRecoveryCode(strings.ToLower(strings.ReplaceAll(code, "-", " ")))
+	if err != nil || normalized != strings.ReplaceAll(code, "-", "") {
+		t.Errorf("NormalizeRecoveryCode() = %s, %v; want %s without separators", normalized, err, code)
+	}
+	if _, err := NormalizeRecoveryCode(code[:len(code)-5]); err == nil {
+		t.Errorf("NormalizeRecoveryCode() of a short code want an error")
+	}
+}
@@ -0,0 +1,291 @@
+package crypto
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// the character classes of the generator
+const (
+	LowerList     = "abcdefghijklmnopqrstuvwxyz"
+	UpperList     = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	DigitList     = "0123456789"
+	consonantList = "bcdfghjklmnpqrstvwxz"
+	vowelList     = "aeiouy"
+)
+
+// the characters that are easily confused when read or typed
+const AmbiguousList = "0OoIl1|`'\""
+
+// PasswordPolicy describe how a password is generated
+// in random mode Length characters are drawn from the Classes (l=lowercase, u=uppercase, d=digit, s=special) with at
+// least Min characters of each class, Pronounceable alternate consonants and vowels and Pattern build the password
+// one character per pattern letter (see GeneratePassword)
+type PasswordPolicy struct {
+	Length           int    `json:"length,omitempty"`
+	Classes          string `json:"classes,omitempty"`
+	MinLower         int    `json:"minlower,omitempty"`
+	MinUpper         int    `json:"minupper,omitempty"`
+	MinDigit         int    `json:"mindigit,omitempty"`
+	MinSpecial       int    `json:"minspecial,omitempty"`
+	Special          string `json:"special,omitempty"`
+	ExcludeAmbiguous bool   `json:"noambiguous,omitempty"`
+	Pronounceable    bool   `json:"pronounceable,omitempty"`
+	Pattern          string `json:"pattern,omitempty"`
+}
+
+// the policy used when none is configured
+var DefaultPolicy = PasswordPolicy{Length: 16, Classes: "luds", MinLower: 1, MinUpper: 1, MinDigit: 1, MinSpecial: 1, Special: SpecialList}
+
+// the policies always available, the configured ones with the same name replace them
+var BuiltinPolicies = map[string]PasswordPolicy{
+	"default":       DefaultPolicy,
+	"alnum":         {Length: 20, Classes: "lud", MinLower: 1, MinUpper: 1, MinDigit: 1, ExcludeAmbiguous: true},
+	"pronounceable": {Length: 14, Pronounceable: true, MinUpper: 1, MinDigit: 2},
+	"pin":           {Pattern: "999999"},
+}
+
+// this function will return the policy called name from the configured policies (the PASSWORDPOLICIES object of the
+// configuration) or the builtin ones, an empty name is the default policy
+func LookupPolicy(name string, policies map[string]interface{}) (PasswordPolicy, error) {
+	if name == "" {
+		name = "default"
+	}
+	if value, ok := policies[name]; ok {
+		var policy PasswordPolicy
+		jsonData, err := json.Marshal(value)
+		if err == nil {
+			err = json.Unmarshal(jsonData, &policy)
+		}
+		if err != nil {
+			return PasswordPolicy{}, fmt.Errorf("password policy %s is not valid: %v", name, err)
+		}
+		return policy, nil
+	}
+	if policy, ok := BuiltinPolicies[name]; ok {
+		return policy, nil
+	}
+	return PasswordPolicy{}, fmt.Errorf("password policy %s not found", name)
+}
+
+// return the names of the configured and builtin policies sorted
+func PolicyNames(policies map[string]interface{}) []string {
+	var rValue []string
+	for name := range BuiltinPolicies {
+		rValue = append(rValue, name)
+	}
+	for name := range policies {
+		if _, ok := BuiltinPolicies[name]; !ok {
+			rValue = append(rValue, name)
+		}
+	}
+	sort.Strings(rValue)
+	return rValue
+}
+
+// remove the ambiguous characters of the list when the policy asks for it
+func (p PasswordPolicy) charset(list string) string {
+	if !p.ExcludeAmbiguous {
+		return list
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(AmbiguousList, r) {
+			return -1
+		}
+		return r
+	}, list)
+}
+
+// return the special characters of the policy
+func (p PasswordPolicy) special() string {
+	if p.Special == "" {
+		return p.charset(SpecialList)
+	}
+	return p.charset(p.Special)
+}
+
+// return a random character of the list
+func randomChar(list string) (byte, error) {
+	if list == "" {
+		return 0, fmt.Errorf("no character to choose from")
+	}
+	n, err := randomIndex(len(list))
+	if err != nil {
+		return 0, err
+	}
+	return list[n], nil
+}
+
+// shuffle the password in place (Fisher-Yates)
+func shuffle(password []byte) error {
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return nil
+}
+
+// this function will generate a password following the policy, the characters come from crypto/rand
+// the pattern letters are C/c an upper/lower consonant, V/v an upper/lower vowel, A/a an upper/lower letter,
+// 9 a digit, # a special character, * any character of the classes and \ escapes the next one, the other
+// characters are kept, "Cvcc-9999" gives something like "Bupt-4821"
+// a policy without Length uses the length of DefaultPolicy
+func GeneratePassword(policy PasswordPolicy) (string, error) {
+	if policy.Pattern != "" {
+		return policy.generatePattern()
+	}
+	if policy.Length <= 0 {
+		policy.Length = DefaultPolicy.Length
+	}
+	if policy.Pronounceable {
+		return policy.generatePronounceable()
+	}
+	return policy.generateRandom()
+}
+
+// draw Length characters from the classes with the minimum count of each class then shuffle them
+func (p PasswordPolicy) generateRandom() (string, error) {
+	classes := []struct {
+		name string
+		list string
+		min  int
+	}{
+		{"l", p.charset(LowerList), p.MinLower},
+		{"u", p.charset(UpperList), p.MinUpper},
+		{"d", p.charset(DigitList), p.MinDigit},
+		{"s", p.special(), p.MinSpecial},
+	}
+	var all string
+	var password []byte
+	for _, class := range classes {
+		if !strings.Contains(p.Classes, class.name) && class.min == 0 {
+			continue
+		}
+		all += class.list
+		for i := 0; i < class.min; i++ {
+			c, err := randomChar(class.list)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, c)
+		}
+	}
+	if all == "" {
+		return "", fmt.Errorf("password policy has no character class")
+	}
+	if p.Length < len(password) {
+		return "", fmt.Errorf("password length %d is shorter than the minimum counts %d", p.Length, len(password))
+	}
+	for len(password) < p.Length {
+		c, err := randomChar(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	if err := shuffle(password); err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
+// alternate consonants and vowels, MinUpper of them are capitalized and MinDigit digits and MinSpecial
+// special characters are added at the end so the password stays easy to read
+func (p PasswordPolicy) generatePronounceable() (string, error) {
+	letters := p.Length - p.MinDigit - p.MinSpecial
+	if letters <= 0 || letters < p.MinUpper {
+		return "", fmt.Errorf("password length %d is too short for a pronounceable password", p.Length)
+	}
+	password := make([]byte, 0, p.Length)
+	for i := 0; i < letters; i++ {
+		list := consonantList
+		if i%2 == 1 {
+			list = vowelList
+		}
+		c, err := randomChar(p.charset(list))
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	//capitalize MinUpper letters chosen among the ones whose capital is allowed
+	var candidates []int
+	for i, c := range password {
+		if strings.ContainsRune(p.charset(UpperList), unicode.ToUpper(rune(c))) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) < p.MinUpper {
+		return "", fmt.Errorf("password length %d is too short for %d uppercase letters", p.Length, p.MinUpper)
+	}
+	for upper := 0; upper < p.MinUpper; upper++ {
+		n, err := randomIndex(len(candidates) - upper)
+		if err != nil {
+			return "", err
+		}
+		i := candidates[n]
+		password[i] = byte(unicode.ToUpper(rune(password[i])))
+		candidates[n] = candidates[len(candidates)-upper-1]
+	}
+	for _, class := range []struct {
+		list string
+		min  int
+	}{{p.charset(DigitList), p.MinDigit}, {p.special(), p.MinSpecial}} {
+		for i := 0; i < class.min; i++ {
+			c, err := randomChar(class.list)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, c)
+		}
+	}
+	return string(password), nil
+}
+
+// build the password one character per pattern letter
+func (p PasswordPolicy) generatePattern() (string, error) {
+	classes := map[rune]string{
+		'C': strings.ToUpper(consonantList),
+		'c': consonantList,
+		'V': strings.ToUpper(vowelList),
+		'v': vowelList,
+		'A': UpperList,
+		'a': LowerList,
+		'9': DigitList,
+		'#': p.special(),
+	}
+	for i, list := range []string{LowerList, UpperList, DigitList, p.special()} {
+		if p.Classes == "" || strings.Contains(p.Classes, "luds"[i:i+1]) {
+			classes['*'] += list
+		}
+	}
+	var password strings.Builder
+	escaped := false
+	for _, r := range p.Pattern {
+		list, ok := classes[r]
+		if escaped || !ok {
+			if r == '\\' && !escaped {
+				escaped = true
+				continue
+			}
+			password.WriteRune(r)
+			escaped = false
+			continue
+		}
+		c, err := randomChar(p.charset(list))
+		if err != nil {
+			return "", err
+		}
+		password.WriteByte(c)
+	}
+	if password.Len() == 0 {
+		return "", fmt.Errorf("password pattern %q gives an empty password", p.Pattern)
+	}
+	return password.String(), nil
+}
@@ -0,0 +1,147 @@
+package crypto
+
+import (
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WrappedKey is a data key encrypted to a public key
+// RSA keys use PKCS#1 v1.5 (the only padding supported by PIV cards), ECDSA keys use an ephemeral ECDH exchange
+type WrappedKey struct {
+	Algorithm string `json:"algorithm"`
+	// Ephemeral is the PKIX encoded ephemeral public key of the ECDH exchange
+	Ephemeral []byte `json:"ephemeral,omitempty"`
+	Key       []byte `json:"key"`
+}
+
+// SharedKeyer is an EC private key able to do an ECDH exchange, as the PIV key management slot does
+type SharedKeyer interface {
+	SharedKey(peer *ecdsa.PublicKey) ([]byte, error)
+}
+
+// SoftwareKey is an EC private key kept in memory, it can replace a smartcard key in tests
+type SoftwareKey struct {
+	*ecdsa.PrivateKey
+}
+
+// return a new P-256 SoftwareKey
+func NewSoftwareKey() (SoftwareKey, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	return SoftwareKey{priv}, err
+}
+
+// compute the ECDH shared secret with the peer public key
+func (k SoftwareKey) SharedKey(peer *ecdsa.PublicKey) ([]byte, error) {
+	priv, err := k.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := peer.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return priv.ECDH(pub)
+}
+
+// return a new random 32 bytes data key
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, key)
+	return key, err
+}
+
+// encrypt the data key to the public key
+func WrapKey(pub gocrypto.PublicKey, dataKey []byte) (WrappedKey, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		key, err := rsa.EncryptPKCS1v15(rand.Reader, pub, dataKey)
+		return WrappedKey{Algorithm: "rsa", Key: key}, err
+	case *ecdsa.PublicKey:
+		ephemeral, err := ecdsa.GenerateKey(pub.Curve, rand.Reader)
+		if err != nil {
+			return WrappedKey{}, err
+		}
+		shared, err := SoftwareKey{ephemeral}.SharedKey(pub)
+		if err != nil {
+			return WrappedKey{}, err
+		}
+		kek := sha256.Sum256(shared)
+		key, err := Seal(kek[:], dataKey)
+		if err != nil {
+			return WrappedKey{}, err
+		}
+		der, err := x509.MarshalPKIXPublicKey(&ephemeral.PublicKey)
+		return WrappedKey{Algorithm: "ecdh", Ephemeral: der, Key: key}, err
+	}
+	return WrappedKey{}, fmt.Errorf("unsupported public key type %T", pub)
+}
+
+// decrypt a data key wrapped by WrapKey
+// priv is a crypto.Decrypter for RSA keys or a SharedKeyer for EC keys (smartcard or SoftwareKey)
+func UnwrapKey(priv gocrypto.PrivateKey, wrapped WrappedKey) ([]byte, error) {
+	switch wrapped.Algorithm {
+	case "rsa":
+		decrypter, ok := priv.(gocrypto.Decrypter)
+		if !ok {
+			return nil, errors.New("private key can not decrypt")
+		}
+		return decrypter.Decrypt(rand.Reader, wrapped.Key, nil)
+	case "ecdh":
+		keyer, ok := priv.(SharedKeyer)
+		if !ok {
+			return nil, errors.New("private key can not do ECDH")
+		}
+		pub, err := x509.ParsePKIXPublicKey(wrapped.Ephemeral)
+		if err != nil {
+			return nil, err
+		}
+		ecPub, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.New("ephemeral key is not an EC key")
+		}
+		shared, err := keyer.SharedKey(ecPub)
+		if err != nil {
+			return nil, err
+		}
+		kek := sha256.Sum256(shared)
+		return Open(kek[:], wrapped.Key)
+	}
+	return nil, fmt.Errorf("unsupported wrapping algorithm %s", wrapped.Algorithm)
+}
+
+// a recovery code is 160 random bits in base32, written in groups of 4 characters
+const recoveryCodeSize = 20
+
+// return a new random recovery code, it unlocks a data key when the smartcard is lost (see DeriveKey)
+func NewRecoveryCode() (string, error) {
+	raw := make([]byte, recoveryCodeSize)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.EncodeToString(raw)
+	var groups []string
+	for i := 0; i < len(code); i += 4 {
+		groups = append(groups, code[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// return the recovery code without the separators and in upper case, an error is returned if it is not valid
+func NormalizeRecoveryCode(code string) (string, error) {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	raw, err := base32.StdEncoding.DecodeString(normalized)
+	if err != nil || len(raw) != recoveryCodeSize {
+		return "", errors.New("recovery code is not valid")
+	}
+	return normalized, nil
+}
@@ -1,7 +1,7 @@
 package crypto
 
 import (
-	"math/rand"
+	"log"
 	"strings"
 )
 
@@ -34,51 +34,30 @@ func Checkpassword(password string, lowercase, uppercase, digit, special bool, s
 }
 
 // build a password generator function that will generate a password based on the given length and complexity attributes
-// the password will be returned as string
+// the password will be returned as string, it contains at least one character of each selected class
 func RandomPassword(lengh int, lowercase, uppercase, digit, special bool, specialList string) string {
 
 	if lengh < 0 {
 		lengh = 12
 	}
-	//build the list of characters
-	var charList string
-	if lowercase {
-		charList = charList + "abcdefghijklmnopqrstuvwxyz"
-	}
-	if uppercase {
-		charList = charList + "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	}
-	if digit {
-		charList = charList + "0123456789"
-	}
-	if special {
-		charList = charList + specialList
+	policy := PasswordPolicy{Length: lengh, Special: specialList}
+	for _, class := range []struct {
+		selected bool
+		min      *int
+	}{{lowercase, &policy.MinLower}, {uppercase, &policy.MinUpper}, {digit, &policy.MinDigit}, {special, &policy.MinSpecial}} {
+		if class.selected {
+			*class.min = 1
+		}
 	}
-	//if charList is empty use default
-	if charList == "" {
-		charList = " "
+	//if no class is selected use spaces
+	if policy.MinLower+policy.MinUpper+policy.MinDigit+policy.MinSpecial == 0 {
+		return strings.Repeat(" ", lengh)
 	}
-	var rValue string
-	// this loop will generate a password until it matches the constraints
-	// the password will be returned as string
-	// this is one possible implementation, there are many others
-	for {
-		//build the password using this constraints
-		// countains at least one lowercase character
-		// countains at least one uppercase character
-		// countains at least one digit
-		// countains at least one special character
-		// countains at least one character from the given list
-		for i := 0; i < lengh; i++ {
-			rValue = rValue + string(charList[rand.Intn(len(charList))])
-		}
-		//check constraints
-		if Checkpassword(rValue, lowercase, uppercase, digit, special, specialList) {
-			break
-		} else {
-			rValue = ""
-			continue
-		}
+	//the password can not be shorter than the number of classes
+	policy.Length = max(lengh, policy.MinLower+policy.MinUpper+policy.MinDigit+policy.MinSpecial)
+	rValue, err := GeneratePassword(policy)
+	if err != nil {
+		log.Fatal(err)
 	}
 	return rValue
 
@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// wordlist.txt is a diceware list of 1296 short words, each line is the 4 dice roll and the word
+// so the list can also be used with real dice
+//
+//go:embed wordlist.txt
+var wordlistFile string
+
+// the words of the diceware list
+var Wordlist = func() []string {
+	var rValue []string
+	for _, line := range strings.Split(strings.TrimSpace(wordlistFile), "\n") {
+		if _, word, found := strings.Cut(line, "\t"); found {
+			rValue = append(rValue, strings.TrimSpace(word))
+		}
+	}
+	return rValue
+}()
+
+// the default number of words of a passphrase (about 62 bits)
+const PassphraseWords = 6
+
+// PassphraseOptions select how the passphrase is built
+type PassphraseOptions struct {
+	Words     int
+	Separator string
+	// Capitalize write each word with a capital letter
+	Capitalize bool
+	// Digit insert a random digit after one of the words
+	Digit bool
+}
+
+// return a random number in [0, n) from crypto/rand
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+// this function will return a random passphrase of diceware words and its entropy in bits
+func RandomPassphrase(options PassphraseOptions) (string, float64, error) {
+	if options.Words <= 0 {
+		options.Words = PassphraseWords
+	}
+	words := make([]string, options.Words)
+	for i := range words {
+		n, err := randomIndex(len(Wordlist))
+		if err != nil {
+			return "", 0, err
+		}
+		words[i] = Wordlist[n]
+		if options.Capitalize {
+			runes := []rune(words[i])
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+	}
+	entropy := float64(options.Words) * math.Log2(float64(len(Wordlist)))
+	if options.Digit {
+		//the digit and the word it follows are random
+		digit, err := randomIndex(10)
+		if err != nil {
+			return "", 0, err
+		}
+		position, err := randomIndex(options.Words)
+		if err != nil {
+			return "", 0, err
+		}
+		words[position] += strconv.Itoa(digit)
+		entropy += math.Log2(float64(10 * options.Words))
+	}
+	return strings.Join(words, options.Separator), entropy, nil
+}
@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// number of PBKDF2 iterations used to derive a key from a passphrase
+const KeyIterations = 210000
+
+// size of the salt to use with DeriveKey
+const SaltSize = 16
+
+// return a new random salt
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	_, err := io.ReadFull(rand.Reader, salt)
+	return salt, err
+}
+
+// derive a 32 bytes AES key from the passphrase using PBKDF2-HMAC-SHA256
+func DeriveKey(passphrase string, salt []byte) []byte {
+	return pbkdf2([]byte(passphrase), salt, KeyIterations, 32)
+}
+
+// PBKDF2 (RFC 8018) with HMAC-SHA256
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+	var rValue []byte
+	buf := make([]byte, 4)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		prf.Write(buf)
+		u := prf.Sum(nil)
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		rValue = append(rValue, t...)
+	}
+	return rValue[:keyLen]
+}
+
+// encrypt the plaintext with AES-GCM, the random nonce is prepended to the result
+func Seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decrypt a value produced by Seal, an error is returned if the key is wrong or the data was modified
+func Open(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed data too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
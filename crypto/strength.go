@@ -0,0 +1,478 @@
+package crypto
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The strength of a password is estimated from the number of guesses an attacker trying the common
+// patterns first would need: the password is split in the cheapest sequence of dictionary words (with l33t
+// substitutions), keyboard patterns, sequences, repeats, dates and brute forced characters (zxcvbn like)
+
+// the strength scores
+const (
+	StrengthVeryWeak = iota
+	StrengthWeak
+	StrengthFair
+	StrengthGood
+	StrengthStrong
+)
+
+// the names of the scores
+var StrengthNames = []string{"very weak", "weak", "fair", "good", "strong"}
+
+// Strength is the estimated strength of a password
+type Strength struct {
+	// Score is from StrengthVeryWeak (0) to StrengthStrong (4)
+	Score int
+	// Guesses is the log10 of the estimated number of guesses
+	Guesses float64
+	// Entropy is the estimated entropy in bits
+	Entropy  float64
+	Feedback []string
+}
+
+// the common passwords and words, the most common first
+var commonWords = strings.Fields(`password 123456 qwerty admin welcome login letmein monkey dragon master
+	sunshine princess football baseball shadow superman batman trustno1 iloveyou abc123 starwars whatever
+	secret passw0rd hello freedom michael jennifer jordan hunter ranger buster soccer harley thomas robert
+	charlie daniel matthew andrew joshua pepper ginger summer winter spring autumn love access flower
+	computer internet google apple orange banana cheese coffee chocolate cookie purple silver golden
+	diamond tiger lion eagle falcon wolf bear horse cat dog fish bird angel devil heaven magic ninja
+	pirate killer hockey tennis golf racing mustang ferrari porsche yankees lakers cowboys liverpool
+	chelsea arsenal barcelona london paris berlin tokyo america canada france germany london
+	january february march april may june july august september october november december
+	monday tuesday wednesday thursday friday saturday sunday
+	user test guest root default changeme system server database network office company vault
+	mypassword mysecret qazwsx zaq1 asdfgh 111111 000000 654321 123123 112233 666666 121212`)
+
+// rank of the common words
+var commonRanks = func() map[string]int {
+	rValue := make(map[string]int)
+	for i, w := range commonWords {
+		if _, ok := rValue[w]; !ok {
+			rValue[w] = i + 1
+		}
+	}
+	return rValue
+}()
+
+// a dictionary with the prefixes of its words and the length of its longest word, the l33t candidates
+// and the substrings tried are bounded by them
+type strengthDictionary struct {
+	ranks    map[string]int
+	prefixes map[string]bool
+	longest  int
+}
+
+// return the dictionary of the ranks
+func newStrengthDictionary(ranks map[string]int) strengthDictionary {
+	rValue := strengthDictionary{ranks: ranks, prefixes: make(map[string]bool)}
+	for w := range ranks {
+		runes := []rune(w)
+		for i := 1; i <= len(runes); i++ {
+			rValue.prefixes[string(runes[:i])] = true
+		}
+		rValue.longest = max(rValue.longest, len(runes))
+	}
+	return rValue
+}
+
+// the dictionary of the common words
+var commonDictionary = newStrengthDictionary(commonRanks)
+
+// the l33t substitutions
+var leetTable = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '3': {'e'}, '6': {'g'}, '9': {'g'}, '1': {'i', 'l'},
+	'!': {'i'}, '|': {'i', 'l'}, '0': {'o'}, '$': {'s'}, '5': {'s'}, '7': {'t'}, '+': {'t'}, '2': {'z'},
+}
+
+// the rows of the keyboard layouts, adjacent keys in a row form a keyboard pattern
+var keyboardRows = []string{
+	"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./",
+	"~!@#$%^&*()_+", "1qaz", "2wsx", "3edc", "4rfv", "5tgb", "6yhn", "7ujm", "8ik,", "9ol.", "0p;/",
+	"789", "456", "123", "741", "852", "963",
+}
+
+// a pattern found in the password
+type strengthMatch struct {
+	start, end int // runes [start, end)
+	guesses    float64
+	pattern    string
+}
+
+// this function will estimate the strength of the password, the user inputs (username, url...) are
+// considered as known words
+func EstimateStrength(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	n := len(runes)
+	if n == 0 {
+		return Strength{Score: StrengthVeryWeak, Feedback: []string{"the password is empty"}}
+	}
+	guesses, used := cheapestSplit(runes, userInputs, make(map[string]float64))
+	rValue := Strength{Guesses: guesses, Entropy: guesses / math.Log10(2)}
+	switch {
+	case guesses < 3:
+		rValue.Score = StrengthVeryWeak
+	case guesses < 6:
+		rValue.Score = StrengthWeak
+	case guesses < 8:
+		rValue.Score = StrengthFair
+	case guesses < 10:
+		rValue.Score = StrengthGood
+	default:
+		rValue.Score = StrengthStrong
+	}
+	rValue.Feedback = strengthFeedback(used, n, rValue.Score)
+	return rValue
+}
+
+// return the log10 of the guesses of the cheapest split of the runes in patterns and the patterns used
+// groups caches the guesses of the repeated groups already estimated
+func cheapestSplit(runes []rune, userInputs []string, groups map[string]float64) (float64, []string) {
+	n := len(runes)
+	matches := findMatches(runes, userInputs, groups)
+	//best[i] is the log10 of the guesses of the cheapest split of runes[:i]
+	best := make([]float64, n+1)
+	from := make([]*strengthMatch, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = best[i-1] + math.Log10(float64(cardinality(runes[i-1:i])))
+		for k := range matches {
+			m := &matches[k]
+			if m.end == i {
+				if cost := best[m.start] + math.Log10(m.guesses); cost < best[i] {
+					best[i], from[i] = cost, m
+				}
+			}
+		}
+	}
+	//the patterns of the cheapest split, from the end
+	var used []string
+	for i := n; i > 0; {
+		if from[i] == nil {
+			i--
+			continue
+		}
+		used = append([]string{from[i].pattern}, used...)
+		i = from[i].start
+	}
+	return best[n], used
+}
+
+// return the feedback for the patterns found, a good password has no feedback
+func strengthFeedback(patterns []string, length, score int) []string {
+	if score >= StrengthGood {
+		return nil
+	}
+	messages := map[string]string{
+		"dictionary": "common passwords and words are easy to guess",
+		"user":       "do not use your username or the site name",
+		"leet":       "predictable substitutions like @ for a do not help much",
+		"keyboard":   "keyboard patterns like qwerty are easy to guess",
+		"sequence":   "sequences like abc or 1234 are easy to guess",
+		"repeat":     "repeats like aaa or abcabc are easy to guess",
+		"date":       "dates and years are easy to guess",
+	}
+	var rValue []string
+	seen := make(map[string]bool)
+	for _, p := range patterns {
+		if !seen[p] {
+			seen[p] = true
+			rValue = append(rValue, messages[p])
+		}
+	}
+	if length < 12 {
+		rValue = append(rValue, "use a longer password (12 characters or more)")
+	}
+	if len(rValue) == 0 {
+		rValue = append(rValue, "add more words or characters")
+	}
+	return rValue
+}
+
+// return the number of possible characters of the brute force of the runes
+func cardinality(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < 128:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	rValue := 0
+	for _, c := range []struct {
+		found bool
+		size  int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if c.found {
+			rValue += c.size
+		}
+	}
+	return rValue
+}
+
+// return all the patterns found in the password
+func findMatches(runes []rune, userInputs []string, groups map[string]float64) []strengthMatch {
+	var rValue []strengthMatch
+	rValue = append(rValue, dictionaryMatches(runes, userInputs)...)
+	rValue = append(rValue, keyboardMatches(runes)...)
+	rValue = append(rValue, sequenceMatches(runes)...)
+	rValue = append(rValue, repeatMatches(runes, groups)...)
+	rValue = append(rValue, dateMatches(runes)...)
+	return rValue
+}
+
+// return the variations of the uppercase letters of the word (password, Password, PASSWORD...)
+func uppercaseVariations(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	//first letter, last letter or all uppercase are the common variations
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(word[0]) || unicode.IsUpper(word[len(word)-1]))) {
+		return 2
+	}
+	return binomialSum(upper+lower, min(upper, lower))
+}
+
+// return the sum of C(n, i) for i from 1 to k
+func binomialSum(n, k int) float64 {
+	rValue, c := 0.0, 1.0
+	for i := 1; i <= k; i++ {
+		c = c * float64(n-i+1) / float64(i)
+		rValue += c
+	}
+	return rValue
+}
+
+// return the dictionary words (with l33t substitutions) of the password
+func dictionaryMatches(runes []rune, userInputs []string) []strengthMatch {
+	dictionary := commonDictionary
+	if len(userInputs) > 0 {
+		ranks := make(map[string]int, len(commonRanks)+len(userInputs))
+		for w, r := range commonRanks {
+			ranks[w] = r
+		}
+		for _, input := range userInputs {
+			for _, w := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+				if len([]rune(w)) >= 3 {
+					ranks[w] = -1
+				}
+			}
+		}
+		dictionary = newStrengthDictionary(ranks)
+	}
+	var rValue []strengthMatch
+	for i := range runes {
+		//no word is longer than the longest one of the dictionary
+		for j := i + 3; j <= len(runes) && j-i <= dictionary.longest; j++ {
+			word := runes[i:j]
+			lower := []rune(strings.ToLower(string(word)))
+			if rank, ok := dictionary.ranks[string(lower)]; ok {
+				pattern := "dictionary"
+				if rank < 0 {
+					rank, pattern = 1, "user"
+				}
+				rValue = append(rValue, strengthMatch{i, j, float64(rank) * uppercaseVariations(word), pattern})
+				continue
+			}
+			//try the l33t substitutions, each substituted character double the guesses
+			if unleet, subs := unleetWord(lower, dictionary); subs > 0 {
+				rank := dictionary.ranks[unleet]
+				if rank < 0 {
+					rank = 1
+				}
+				rValue = append(rValue, strengthMatch{i, j, float64(rank) * uppercaseVariations(word) * math.Pow(2, float64(subs)), "leet"})
+			}
+		}
+	}
+	return rValue
+}
+
+// return the dictionary word matching the l33t word and the number of substitutions, 0 when not found
+// the candidates that are not the prefix of a dictionary word are dropped as soon as they are built
+func unleetWord(word []rune, dictionary strengthDictionary) (string, int) {
+	candidates := []string{""}
+	subs := 0
+	for _, r := range word {
+		options, ok := leetTable[r]
+		if !ok {
+			options = []rune{r}
+		} else {
+			subs++
+		}
+		var next []string
+		for _, c := range candidates {
+			for _, o := range options {
+				if dictionary.prefixes[c+string(o)] {
+					next = append(next, c+string(o))
+				}
+			}
+		}
+		if len(next) == 0 {
+			return "", 0
+		}
+		candidates = next
+	}
+	if subs == 0 {
+		return "", 0
+	}
+	for _, c := range candidates {
+		if _, ok := dictionary.ranks[c]; ok {
+			return c, subs
+		}
+	}
+	return "", 0
+}
+
+// check if a and b are next to each other on a keyboard row
+func adjacentKeys(a, b rune) bool {
+	a, b = unicode.ToLower(a), unicode.ToLower(b)
+	for _, row := range keyboardRows {
+		i, j := strings.IndexRune(row, a), strings.IndexRune(row, b)
+		if i >= 0 && j >= 0 && (i-j == 1 || j-i == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// return the runs of adjacent keys of 3 keys or more
+func keyboardMatches(runes []rune) []strengthMatch {
+	var rValue []strengthMatch
+	for i := 0; i < len(runes); {
+		j := i + 1
+		for j < len(runes) && adjacentKeys(runes[j-1], runes[j]) {
+			j++
+		}
+		if j-i >= 3 {
+			//about 94 starting keys, the direction changes are few
+			rValue = append(rValue, strengthMatch{i, j, 94 * float64(j-i) * 2 * uppercaseVariations(runes[i:j]), "keyboard"})
+		}
+		i = j
+	}
+	return rValue
+}
+
+// return the sequences (abc, 9876, ace) of 3 characters or more
+func sequenceMatches(runes []rune) []strengthMatch {
+	var rValue []strengthMatch
+	for i := 0; i+2 < len(runes); {
+		delta := runes[i+1] - runes[i]
+		j := i + 1
+		for j < len(runes) && runes[j]-runes[j-1] == delta && delta != 0 && delta >= -2 && delta <= 2 {
+			j++
+		}
+		if j-i >= 3 {
+			base := 26.0
+			if unicode.IsDigit(runes[i]) {
+				base = 10
+			}
+			rValue = append(rValue, strengthMatch{i, j, base * float64(j-i) * 2, "sequence"})
+			i = j - 1
+			continue
+		}
+		i++
+	}
+	return rValue
+}
+
+// return the repeats of a character or of a group of characters (aaa, abcabc)
+// only the groups that are not themselves a repeat are tried, "aaaa" is 4 "a" and not 2 "aa"
+func repeatMatches(runes []rune, groups map[string]float64) []strengthMatch {
+	var rValue []strengthMatch
+	for i := range runes {
+		for size := 1; size <= (len(runes)-i)/2; size++ {
+			count := 1
+			for i+(count+1)*size <= len(runes) && string(runes[i+count*size:i+(count+1)*size]) == string(runes[i:i+size]) {
+				count++
+			}
+			if count < 2 || (size == 1 && count < 3) || isRepeat(runes[i:i+size]) {
+				continue
+			}
+			//the guesses of the repeated group are estimated with its own best split
+			group := string(runes[i : i+size])
+			guesses, ok := groups[group]
+			if !ok {
+				guesses, _ = cheapestSplit(runes[i:i+size], nil, groups)
+				groups[group] = guesses
+			}
+			rValue = append(rValue, strengthMatch{i, i + count*size, math.Pow(10, guesses) * float64(count), "repeat"})
+		}
+	}
+	return rValue
+}
+
+// check if the group is a smaller group repeated
+func isRepeat(group []rune) bool {
+	for size := 1; size <= len(group)/2; size++ {
+		if len(group)%size == 0 && strings.Repeat(string(group[:size]), len(group)/size) == string(group) {
+			return true
+		}
+	}
+	return false
+}
+
+// return the years (1900-2099) and the dates (ddmmyyyy, yyyymmdd, ddmmyy...) with or without separators
+func dateMatches(runes []rune) []strengthMatch {
+	var rValue []strengthMatch
+	for i := range runes {
+		for j := i + 4; j <= len(runes) && j-i <= 10; j++ {
+			text := string(runes[i:j])
+			digits := strings.Map(func(r rune) rune {
+				if r == '/' || r == '-' || r == '.' || r == '_' || r == ' ' {
+					return -1
+				}
+				return r
+			}, text)
+			if _, err := strconv.Atoi(digits); err != nil || strings.HasPrefix(digits, "-") {
+				continue
+			}
+			switch {
+			case len(digits) == 4 && len(text) == 4 && isYear(digits):
+				rValue = append(rValue, strengthMatch{i, j, 200, "date"})
+			case (len(digits) == 6 || len(digits) == 8) && isDate(digits):
+				guesses := 365.0 * 200
+				if len(text) != len(digits) {
+					guesses *= 4 //the separator
+				}
+				rValue = append(rValue, strengthMatch{i, j, guesses, "date"})
+			}
+		}
+	}
+	return rValue
+}
+
+// check if the 4 digits are a year between 1900 and 2099
+func isYear(digits string) bool {
+	return strings.HasPrefix(digits, "19") || strings.HasPrefix(digits, "20")
+}
+
+// check if the digits are a date: ddmmyyyy, mmddyyyy, yyyymmdd or the same with a 2 digits year
+func isDate(digits string) bool {
+	day := func(s string) bool { d, _ := strconv.Atoi(s); return d >= 1 && d <= 31 }
+	month := func(s string) bool { m, _ := strconv.Atoi(s); return m >= 1 && m <= 12 }
+	if len(digits) == 8 {
+		return (isYear(digits[4:]) && ((day(digits[:2]) && month(digits[2:4])) || (month(digits[:2]) && day(digits[2:4])))) ||
+			(isYear(digits[:4]) && month(digits[4:6]) && day(digits[6:]))
+	}
+	return (day(digits[:2]) && month(digits[2:4])) || (month(digits[:2]) && day(digits[2:4])) ||
+		(month(digits[2:4]) && day(digits[4:]))
+}
@@ -0,0 +1,169 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// default TOTP parameters (RFC 6238), the ones used by most authenticator applications
+const (
+	TOTPDigits    = 6
+	TOTPPeriod    = 30
+	TOTPAlgorithm = "SHA1"
+)
+
+// TOTP is a time based one time password seed
+type TOTP struct {
+	Secret    string // base32 encoded key
+	Digits    int
+	Period    int
+	Algorithm string // SHA1, SHA256 or SHA512
+	Issuer    string
+	Account   string
+}
+
+// this function will read an otpauth://totp/ URI or a base32 secret and return the TOTP with its defaults
+func ParseTOTP(value string) (TOTP, error) {
+	value = strings.TrimSpace(value)
+	rValue := TOTP{Digits: TOTPDigits, Period: TOTPPeriod, Algorithm: TOTPAlgorithm}
+	if !strings.HasPrefix(strings.ToLower(value), "otpauth://") {
+		rValue.Secret = value
+		return rValue, rValue.check()
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return rValue, err
+	}
+	if !strings.EqualFold(u.Host, "totp") {
+		return rValue, fmt.Errorf("otpauth type %q is not supported, only totp", u.Host)
+	}
+	//the label is "issuer:account" or "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, found := strings.Cut(label, ":"); found {
+		rValue.Issuer, rValue.Account = issuer, strings.TrimSpace(account)
+	} else {
+		rValue.Account = label
+	}
+	query := u.Query()
+	rValue.Secret = query.Get("secret")
+	if issuer := query.Get("issuer"); issuer != "" {
+		rValue.Issuer = issuer
+	}
+	if digits := query.Get("digits"); digits != "" {
+		if rValue.Digits, err = strconv.Atoi(digits); err != nil {
+			return rValue, fmt.Errorf("digits %q is not a number", digits)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		if rValue.Period, err = strconv.Atoi(period); err != nil {
+			return rValue, fmt.Errorf("period %q is not a number", period)
+		}
+	}
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		rValue.Algorithm = strings.ToUpper(algorithm)
+	}
+	return rValue, rValue.check()
+}
+
+// check the parameters of the TOTP
+func (t TOTP) check() error {
+	if t.Secret == "" {
+		return fmt.Errorf("the TOTP secret is empty")
+	}
+	if _, err := t.key(); err != nil {
+		return fmt.Errorf("the TOTP secret is not valid base32: %w", err)
+	}
+	if t.Digits < 6 || t.Digits > 10 {
+		return fmt.Errorf("TOTP digits %d is not between 6 and 10", t.Digits)
+	}
+	if t.Period <= 0 {
+		return fmt.Errorf("TOTP period %d must be positive", t.Period)
+	}
+	_, err := hashFunc(t.Algorithm)
+	return err
+}
+
+// return the key decoded from base32, spaces and padding are optional
+func (t TOTP) key() ([]byte, error) {
+	secret := strings.ToUpper(strings.ReplaceAll(t.Secret, " ", ""))
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+}
+
+// return the hash of the HMAC for the algorithm name
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	switch strings.ToUpper(algorithm) {
+	case "", "SHA1":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("TOTP algorithm %q is not supported", algorithm)
+}
+
+// return the otpauth URI of the TOTP, used to store it
+func (t TOTP) URI() string {
+	label := t.Account
+	if t.Issuer != "" {
+		label = t.Issuer + ":" + t.Account
+	}
+	query := url.Values{}
+	query.Set("secret", strings.ToUpper(strings.ReplaceAll(t.Secret, " ", "")))
+	if t.Issuer != "" {
+		query.Set("issuer", t.Issuer)
+	}
+	query.Set("algorithm", strings.ToUpper(t.Algorithm))
+	query.Set("digits", strconv.Itoa(t.Digits))
+	query.Set("period", strconv.Itoa(t.Period))
+	u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// HOTP (RFC 4226) of the counter with the given number of digits
+func HOTP(key []byte, counter uint64, digits int, algorithm string) (string, error) {
+	h, err := hashFunc(algorithm)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(h, key)
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	//dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	code := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	mod := uint64(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod), nil
+}
+
+// this function will return the code of the TOTP (RFC 6238) at the given time
+func (t TOTP) Code(now time.Time) (string, error) {
+	if err := t.check(); err != nil {
+		return "", err
+	}
+	key, _ := t.key()
+	return HOTP(key, uint64(now.Unix())/uint64(t.Period), t.Digits, t.Algorithm)
+}
+
+// return the number of seconds the code of the given time is still valid
+func (t TOTP) Remaining(now time.Time) int {
+	if t.Period <= 0 {
+		return 0
+	}
+	return t.Period - int(now.Unix()%int64(t.Period))
+}
@@ -0,0 +1,78 @@
+package interactif
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abruno06/myvault/securestore"
+)
+
+// ask the user a value, the default is returned for an empty answer
+func askWithDefault(prompt, defaultValue string) string {
+	fmt.Printf("%s (%s): ", prompt, defaultValue)
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	if value := strings.TrimSpace(scanner.Text()); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// this function will ask a secret ID and a file and attach the file to the secret
+func AttachFileInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	secretID := AskSecret()
+	fmt.Print("Enter File to attach: ")
+	var path string
+	fmt.Scanln(&path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	name := askWithDefault("Enter Attachment Name", filepath.Base(path))
+	if err := securestore.AttachFile(ctx, secstore, secretID, name, data); err != nil {
+		return err
+	}
+	fmt.Printf("%s attached to %s (%d bytes)\n", name, secretID, len(data))
+	return nil
+}
+
+// this function will ask a secret ID and list its attachments
+func ListAttachmentsInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	attachments, err := securestore.ListAttachments(ctx, secstore, AskSecret())
+	if err != nil {
+		return err
+	}
+	securestore.DisplayAttachments(attachments)
+	return nil
+}
+
+// this function will ask a secret ID and an attachment and write it to a file readable only by the user
+func ExtractAttachmentInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	secretID := AskSecret()
+	fmt.Print("Enter Attachment Name: ")
+	var name string
+	fmt.Scanln(&name)
+	path := askWithDefault("Enter File to write", name)
+	if err := securestore.ExtractAttachment(ctx, secstore, secretID, name, path); err != nil {
+		return err
+	}
+	fmt.Printf("%s written to %s\n", name, path)
+	return nil
+}
+
+// this function will ask a secret ID and an attachment and delete the attachment
+func DeleteAttachmentInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	secretID := AskSecret()
+	fmt.Print("Enter Attachment Name: ")
+	var name string
+	fmt.Scanln(&name)
+	if err := securestore.DeleteAttachment(ctx, secstore, secretID, name); err != nil {
+		return err
+	}
+	fmt.Printf("%s deleted from %s\n", name, secretID)
+	return nil
+}
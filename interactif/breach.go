@@ -0,0 +1,57 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/crypto"
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this function will check the credential against the Have I Been Pwned file (config HIBPFILE)
+// a breached credential is reported, an error is returned when HIBPMODE is refuse
+func checkBreached(credential string) error {
+	path := config.ReadHIBPFile()
+	if path == "" || credential == "" {
+		return nil
+	}
+	list, err := crypto.OpenBreachList(path)
+	if err != nil {
+		fmt.Printf("Warning: breached passwords check skipped: %v\n", err)
+		return nil
+	}
+	defer list.Close()
+	count, err := list.Count(credential)
+	if err != nil {
+		fmt.Printf("Warning: breached passwords check skipped: %v\n", err)
+		return nil
+	}
+	if count == 0 {
+		return nil
+	}
+	if config.ReadHIBPMode() == "refuse" {
+		return fmt.Errorf("the credential was seen %d times in breaches, it is refused", count)
+	}
+	fmt.Printf("Warning: the credential was seen %d times in breaches\n", count)
+	return nil
+}
+
+// this function will check every stored credential against the Have I Been Pwned file
+func AuditBreachedInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	path := config.ReadHIBPFile()
+	if path == "" {
+		return fmt.Errorf("HIBPFILE is not set")
+	}
+	list, err := crypto.OpenBreachList(path)
+	if err != nil {
+		return err
+	}
+	defer list.Close()
+	entries, err := securestore.AuditBreached(ctx, secstore, list)
+	if err != nil {
+		return err
+	}
+	securestore.DisplayBreachAudit(entries)
+	return nil
+}
@@ -0,0 +1,81 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/securestore"
+	"github.com/abruno06/myvault/smartcard"
+
+	"github.com/go-piv/piv-go/piv"
+)
+
+// return the opened yubikey or open the first one plugged in, usage tells what the key management slot is needed for
+func keyManagementYubikey(yk *piv.YubiKey, usage string) (*piv.YubiKey, error) {
+	if yk != nil {
+		return yk, nil
+	}
+	if !smartcard.CheckYubikey() {
+		return nil, fmt.Errorf("no Yubikey found to %s", usage)
+	}
+	return smartcard.OpenYubikey(SelectSmartcard()), nil
+}
+
+// this function will ask how the offline cache is locked: with a passphrase or with the public key of the
+// yubikey key management slot
+func AskCacheLock(yk *piv.YubiKey) (securestore.CacheLock, error) {
+	var lock securestore.CacheLock
+	if config.ReadCacheLock() == "yubikey" {
+		key, err := keyManagementYubikey(yk, "lock the offline cache")
+		if err != nil {
+			return lock, err
+		}
+		if key != yk {
+			defer key.Close()
+		}
+		lock.PublicKey, err = smartcard.KeyManagementPublicKey(key)
+		return lock, err
+	}
+	fmt.Println("Offline cache")
+	lock.Passphrase = ReadPassphrase()
+	return lock, nil
+}
+
+// this function will save the secrets into the offline cache locked with lock
+func SaveCacheInteractive(ctx context.Context, secstore securestore.SecretStore, lock securestore.CacheLock) error {
+	if err := securestore.SaveCache(ctx, secstore, config.ReadCacheFile(), lock); err != nil {
+		return err
+	}
+	fmt.Printf("Offline cache saved in %s\n", config.ReadCacheFile())
+	return nil
+}
+
+// this function will unlock the offline cache and return a read only SecretStore
+// pin is the pin already typed by the user, it is asked if empty
+func OpenCacheInteractive(yk *piv.YubiKey, pin string) (securestore.SecretStore, error) {
+	mode, err := securestore.CacheLockMode(config.ReadCacheFile())
+	if err != nil {
+		return securestore.SecretStore{}, err
+	}
+	var lock securestore.CacheLock
+	if mode == "yubikey" {
+		key, err := keyManagementYubikey(yk, "lock the offline cache")
+		if err != nil {
+			return securestore.SecretStore{}, err
+		}
+		if key != yk {
+			defer key.Close()
+		}
+		if pin == "" {
+			pin = ReadPin()
+		}
+		if _, lock.PrivateKey, err = smartcard.KeyManagementKey(key, pin); err != nil {
+			return securestore.SecretStore{}, err
+		}
+		// the private key is used by OpenCache before the yubikey is closed
+		return securestore.OpenCache(config.ReadCacheFile(), lock)
+	}
+	lock.Passphrase = ReadPassphrase()
+	return securestore.OpenCache(config.ReadCacheFile(), lock)
+}
@@ -0,0 +1,68 @@
+package interactif
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abruno06/myvault/secret"
+)
+
+// return the human fields that differ between the two secrets
+func changedFields(oldSecret, newSecret secret.Secret) []string {
+	oldMap := secret.ConvertFromSecret(oldSecret)
+	newMap := secret.ConvertFromSecret(newSecret)
+	var rValue []string
+	seen := make(map[string]bool)
+	fields := append([]string{"Type"}, secret.HumanFieldNames(oldSecret.Type)...)
+	for _, field := range append(fields, secret.HumanFieldNames(newSecret.Type)...) {
+		if !seen[field] && oldMap[field] != newMap[field] {
+			rValue = append(rValue, field)
+		}
+		seen[field] = true
+	}
+	return rValue
+}
+
+// merge the user changes on top of the secret stored in vault
+// a field changed by the user (compared to base) is kept, any other field takes the stored value
+func mergeSecret(base, mine, theirs secret.Secret) secret.Secret {
+	rMap := secret.ConvertFromSecret(theirs)
+	mineMap := secret.ConvertFromSecret(mine)
+	for _, field := range changedFields(base, mine) {
+		rMap[field] = mineMap[field]
+	}
+	rMap["LastUpdate"] = mineMap["LastUpdate"]
+	rMap["LastUpdateBy"] = mineMap["LastUpdateBy"]
+	rValue, _ := secret.ConvertToSecret(rMap)
+	return rValue
+}
+
+// display the changes made in vault since the user read the secret, credentials are masked
+func displayChanges(base, theirs, mine secret.Secret) {
+	baseMap := secret.ConvertFromSecret(base)
+	theirsMap := secret.ConvertFromSecret(theirs)
+	mineFields := make(map[string]bool)
+	for _, field := range changedFields(base, mine) {
+		mineFields[field] = true
+	}
+	fmt.Printf("Modified by %s at %s\n", theirs.LastUpdateBy, theirs.LastUpdate.UTC().Format("2006-01-02 15:04:05"))
+	for _, field := range changedFields(base, theirs) {
+		oldValue, newValue := baseMap[field], theirsMap[field]
+		if secret.IsSecretField(base.Type, field) || secret.IsSecretField(theirs.Type, field) {
+			oldValue, newValue = "********", "********"
+		}
+		conflict := ""
+		if mineFields[field] {
+			conflict = " (also changed by you)"
+		}
+		fmt.Printf("  %s: %s -> %s%s\n", field, oldValue, newValue, conflict)
+	}
+}
+
+// ask the user how to resolve a conflict: m=merge, o=overwrite, anything else abort
+func askConflictAction() string {
+	fmt.Print("Merge your changes (m), overwrite with your version (o) or abort (a): ")
+	var action string
+	fmt.Scanln(&action)
+	return strings.ToLower(action)
+}
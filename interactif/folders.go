@@ -0,0 +1,42 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abruno06/myvault/securestore"
+)
+
+// ask the user a folder, empty is the root folder
+func AskFolder() string {
+	fmt.Print("Enter Folder (empty for all): ")
+	var folder string
+	fmt.Scanln(&folder)
+	return folder
+}
+
+// this function will ask the user a folder and display its secrets as a tree
+func TreeInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	return securestore.ListSecretsTree(ctx, secstore, AskFolder())
+}
+
+// this function will ask the user a folder and list its secrets
+func ListFolderInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	return securestore.ListFolder(ctx, secstore, AskFolder())
+}
+
+// this function will ask the user a secret or a folder and move it
+// a folder ends with "/", moving a secret to a folder ("dest/") keep its name
+func MoveSecretInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	fmt.Print("Enter Secret ID or Folder to move (folder ends with /): ")
+	var from string
+	fmt.Scanln(&from)
+	fmt.Print("Enter new Secret ID or Folder: ")
+	var to string
+	fmt.Scanln(&to)
+	count, err := securestore.MoveSecret(ctx, secstore, from, to)
+	if count > 0 {
+		fmt.Printf("%d secret(s) moved from %s to %s\n", count, from, to)
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/crypto"
+	"github.com/abruno06/myvault/securestore"
+)
+
+// return true and the policy name when the value asks for a generated credential ("*" or "*policy")
+func isGenerateRequest(value string) (string, bool) {
+	if !strings.HasPrefix(value, "*") || value == "**" {
+		return "", false
+	}
+	return strings.TrimPrefix(value, "*"), true
+}
+
+// generate a credential after asking the user for the password policy
+func RandomPolicyPassword(ctx context.Context, secstore securestore.SecretStore) {
+	fmt.Printf("Policies: %s (vault:<name> for a vault password policy)\n", strings.Join(crypto.PolicyNames(config.ReadPasswordPolicies()), ", "))
+	fmt.Print("Enter Policy (empty for the default): ")
+	var name string
+	fmt.Scanln(&name)
+	password, err := securestore.GeneratePassword(ctx, secstore, name)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	fmt.Printf("Password: %s\n", password)
+}
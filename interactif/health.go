@@ -0,0 +1,18 @@
+package interactif
+
+import (
+	"context"
+	"time"
+
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this function will display the security health report of the secrets
+func HealthReportInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	report, err := securestore.HealthCheck(ctx, secstore, time.Now())
+	if err != nil {
+		return err
+	}
+	securestore.DisplayHealthReport(report)
+	return nil
+}
@@ -0,0 +1,57 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this function will display the version history of a secret, or of the whole app if no secret ID is given
+func SecretHistoryInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	fmt.Print("Enter Secret ID (empty for the whole app): ")
+	var secretID string
+	fmt.Scanln(&secretID)
+	return securestore.ListSecretHistory(ctx, secstore, secretID)
+}
+
+// this function will ask a secret ID and two versions and display the field changes between them
+func DiffSecretInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	secretID := AskSecret()
+	var from, to int64
+	fmt.Print("Enter From Version: ")
+	fmt.Scanln(&from)
+	fmt.Print("Enter To Version: ")
+	fmt.Scanln(&to)
+	fmt.Print("Show credentials in clear (y/N): ")
+	var show string
+	fmt.Scanln(&show)
+	changes, err := securestore.DiffSecretVersions(ctx, secstore, secretID, from, to, strings.ToLower(show) == "y")
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("No difference")
+	}
+	for _, c := range changes {
+		fmt.Printf("%s: %s -> %s\n", c.Field, c.Old, c.New)
+	}
+	return nil
+}
+
+// this function will ask a secret ID and a version and restore the secret to the value it had at that version
+func RestoreSecretInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	secretID := AskSecret()
+	if err := securestore.ListSecretHistory(ctx, secstore, secretID); err != nil {
+		return err
+	}
+	fmt.Print("Enter Version to restore: ")
+	var version int64
+	fmt.Scanln(&version)
+	err := securestore.RestoreSecretVersion(ctx, secstore, secretID, version)
+	if err == nil {
+		fmt.Printf("Secret ID: %s restored to version %d\n", secretID, version)
+	}
+	return err
+}
@@ -1,6 +1,11 @@
 package interactif
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/abruno06/myvault/secret"
+)
 
 // test this package
 type EmulateInteractif struct {
@@ -39,3 +44,29 @@ func TestAskSecretI(t *testing.T) {
 		})
 	}
 }
+
+// test the function mergeSecret
+func TestMergeSecret(t *testing.T) {
+	base := secret.Secret{Username: "user", Credential: "pwd", URL: "url", Comment: "comment"}
+	mine := secret.Secret{Username: "user", Credential: "newpwd", URL: "url", Comment: "comment", LastUpdateBy: "me"}
+	theirs := secret.Secret{Username: "user", Credential: "pwd", URL: "newurl", Comment: "comment", LastUpdateBy: "them"}
+	merged := mergeSecret(base, mine, theirs)
+	if merged.Credential != "newpwd" || merged.URL != "newurl" || merged.LastUpdateBy != "me" {
+		t.Errorf("mergeSecret() = %v; want Credential newpwd, URL newurl, LastUpdateBy me", merged)
+	}
+	if fields := changedFields(base, theirs); len(fields) != 1 || fields[0] != "URL" {
+		t.Errorf("changedFields() = %v; want [URL]", fields)
+	}
+}
+
+// test the function promptFieldNames
+func TestPromptFieldNames(t *testing.T) {
+	want := []string{"Username", "URL", "Credential", "Comment", "Tags", "OTP", "MaxAge"}
+	if got := promptFieldNames(secret.TypeLogin); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("promptFieldNames() = %v; want %v", got, want)
+	}
+	//a type without credential keep its order
+	if got, want := promptFieldNames("apikey"), secret.HumanFieldNames("apikey"); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("promptFieldNames() = %v; want %v", got, want)
+	}
+}
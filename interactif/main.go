@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/abruno06/myvault/config"
@@ -36,8 +38,8 @@ func (i DefaultInteractif) Print(s string) {
 
 // this package will contain all the functions to interact with the user
 // it will be used by the main.go file
-var SecretFieldNames = []string{"Username", "Credential", "URL", "LastUpdate", "LastUpdateBy", "Comment"}
-var SecretHumanFieldNames = []string{"Username", "Credential", "URL", "Comment"}
+var SecretFieldNames = []string{"Username", "Credential", "URL", "LastUpdate", "LastUpdateBy", "Comment", "Tags", "OTP", "MaxAge"}
+var SecretHumanFieldNames = []string{"Username", "Credential", "URL", "Comment", "Tags", "OTP", "MaxAge"}
 
 const AskSecretID = "Enter Secret ID: "
 
@@ -65,32 +67,67 @@ func SelectSmartcard() string {
 }
 
 // this function will ask the user to enter the value of the field and return the value as map[string]string
-func AskUserwithPrevious(Previous map[string]string) map[string]string {
+// the fields are the ones of the type of the secret (Previous["Type"]), an empty answer keep the previous value
+// the generated credentials use the password policies of the store
+func AskUserwithPrevious(ctx context.Context, secstore securestore.SecretStore, Previous map[string]string) map[string]string {
 	scanner := bufio.NewScanner(os.Stdin)
 	fieldValues := make(map[string]string)
-	for key, field := range Previous {
+	typeName := Previous["Type"]
+	for _, key := range promptFieldNames(typeName) {
+		field := Previous[key]
 		var value string
-		if key == "Credential" {
-			rndPwd := crypto.RandomPassword(12, true, true, true, true, "!@#$%^&*()_+-")
-			fmt.Printf("Enter %s: (%s) (* if you want random) ", key, field)
-			scanner.Scan()
-			value = scanner.Text()
-			if value == "*" {
-				value = rndPwd
-			} else if value == "" {
-				value = field
+		if secret.IsMultilineField(typeName, key) {
+			fmt.Printf("Enter %s: (%d line(s), end with a single \".\", empty to keep) ", key, strings.Count(field, "\n")+1)
+			value = readMultiline(scanner)
+		} else if key == "OTP" {
+			value = askOTP(scanner, fmt.Sprintf("Enter %s: (%s) (otpauth:// URI or base32 secret, - to remove) ", key, maskValue(field)))
+			if value == "-" {
+				fieldValues[key] = ""
+				continue
+			}
+		} else if key == "MaxAge" {
+			value = askMaxAge(scanner, fmt.Sprintf("Enter %s: (%s) (90d or 720h, - to remove) ", key, field))
+			if value == "-" {
+				fieldValues[key] = ""
+				continue
+			}
+		} else if key == "Credential" {
+			for {
+				fmt.Printf("Enter %s: (%s) (* if you want random, *policy or *vault:policy to use a password policy, ** for a passphrase) ", key, field)
+				scanner.Scan()
+				value = scanner.Text()
+				if policy, generate := isGenerateRequest(value); generate {
+					password, err := securestore.GeneratePassword(ctx, secstore, policy)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					value = password
+				} else if value == "**" {
+					passphrase, entropy, err := crypto.RandomPassphrase(defaultPassphrase)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					fmt.Printf("Passphrase: %s (%.1f bits)\n", passphrase, entropy)
+					value = passphrase
+				}
+				//a typed credential must be strong enough
+				if value == "" || value == field || acceptStrength(value, fieldValues["Username"], fieldValues["URL"]) {
+					break
+				}
 			}
-			fieldValues[key] = value
 		} else {
 			fmt.Printf("Enter %s: (%s) ", key, field)
 			scanner.Scan()
 			value = scanner.Text()
-			if value == "" {
-				value = field
-			}
+		}
+		if value == "" {
+			value = field
 		}
 		fieldValues[key] = value
 	}
+	fieldValues["Type"] = typeName
 	//fix the automatic fields
 	fieldValues["LastUpdate"] = time.Now().Format("2006-01-02T15:04:05.999999-07:00")
 	fieldValues["LastUpdateBy"] = config.User
@@ -100,18 +137,39 @@ func AskUserwithPrevious(Previous map[string]string) map[string]string {
 }
 
 // this function will ask the user to enter the value of the field and return the value as map[string]string
-func AskUser() map[string]string {
+// the type of the secret is asked first, it select the fields to enter
+// the generated credentials use the password policies of the store
+func AskUser(ctx context.Context, secstore securestore.SecretStore) map[string]string {
 	scanner := bufio.NewScanner(os.Stdin)
 	fieldValues := make(map[string]string)
-	for _, field := range SecretHumanFieldNames {
+	typeName := askType(scanner)
+	for _, field := range promptFieldNames(typeName) {
 		var value string
-		if field == "Credential" {
-			rndPwd := crypto.RandomPassword(10, true, true, true, true, "!@#$%^&*()_+-")
-			fmt.Printf("Enter %s (or hit enter to have autogenerated) ", field)
-			scanner.Scan()
-			value = scanner.Text()
-			if value == "" {
-				value = rndPwd
+		if secret.IsMultilineField(typeName, field) {
+			fmt.Printf("Enter %s (end with a single \".\" line): ", field)
+			value = readMultiline(scanner)
+		} else if field == "OTP" {
+			value = askOTP(scanner, fmt.Sprintf("Enter %s (otpauth:// URI or base32 secret, empty for none): ", field))
+		} else if field == "MaxAge" {
+			value = askMaxAge(scanner, fmt.Sprintf("Enter %s (90d or 720h, empty for the default rotation): ", field))
+		} else if field == "Credential" {
+			for {
+				fmt.Printf("Enter %s (or hit enter to have autogenerated, *policy or *vault:policy to use a password policy) ", field)
+				scanner.Scan()
+				value = scanner.Text()
+				if policy, generate := isGenerateRequest(value); generate || value == "" {
+					password, err := securestore.GeneratePassword(ctx, secstore, policy)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					value = password
+					break
+				}
+				//a typed credential must be strong enough
+				if acceptStrength(value, fieldValues["Username"], fieldValues["URL"]) {
+					break
+				}
 			}
 		} else {
 			fmt.Printf("Enter %s: ", field)
@@ -120,12 +178,126 @@ func AskUser() map[string]string {
 		}
 		fieldValues[field] = value
 	}
+	fieldValues["Type"] = typeName
 	//fix the automatic fields
 	fieldValues["LastUpdate"] = time.Now().Format("2006-01-02T15:04:05.999999-07:00")
 	fieldValues["LastUpdateBy"] = config.User
 	return fieldValues
 }
 
+// return the fields to ask for a type, the URL is asked before the Credential so the strength check can use it
+func promptFieldNames(typeName string) []string {
+	fields := secret.HumanFieldNames(typeName)
+	credential, url := -1, -1
+	for i, f := range fields {
+		switch f {
+		case "Credential":
+			credential = i
+		case "URL":
+			url = i
+		}
+	}
+	if credential < 0 || url < credential {
+		return fields
+	}
+	ordered := append([]string{}, fields[:credential]...)
+	ordered = append(ordered, "URL")
+	for _, f := range fields[credential:] {
+		if f != "URL" {
+			ordered = append(ordered, f)
+		}
+	}
+	return ordered
+}
+
+// ask the type of the secret, login is the default
+func askType(scanner *bufio.Scanner) string {
+	fmt.Println("Select Secret Type (Default is login)")
+	for i, t := range secret.SecretTypes {
+		fmt.Printf("%d. %s (%s)\n", i+1, t.Name, t.Description)
+	}
+	fmt.Print("Enter Type Number: ")
+	scanner.Scan()
+	choice, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+	if err != nil || choice < 1 || choice > len(secret.SecretTypes) {
+		return secret.TypeLogin
+	}
+	return secret.SecretTypes[choice-1].Name
+}
+
+// display the strength of a credential typed by the user, false if it is below PASSWORDMINSCORE
+// the user inputs (username, url) are known to an attacker
+func acceptStrength(credential string, userInputs ...string) bool {
+	strength := crypto.EstimateStrength(credential, userInputs...)
+	fmt.Printf("Strength: %d/4 (%s)\n", strength.Score, crypto.StrengthNames[strength.Score])
+	for _, feedback := range strength.Feedback {
+		fmt.Printf("  - %s\n", feedback)
+	}
+	if min := config.ReadPasswordMinScore(); strength.Score < min {
+		fmt.Printf("The credential is refused, the minimum strength is %d/4 (%s)\n", min, crypto.StrengthNames[min])
+		return false
+	}
+	return true
+}
+
+// ask the TOTP seed until it is valid, the seed is returned as an otpauth URI
+func askOTP(scanner *bufio.Scanner, prompt string) string {
+	for {
+		fmt.Print(prompt)
+		scanner.Scan()
+		value := strings.TrimSpace(scanner.Text())
+		if value == "" || value == "-" {
+			return value
+		}
+		totp, err := crypto.ParseTOTP(value)
+		if err == nil {
+			return totp.URI()
+		}
+		fmt.Printf("Invalid OTP: %v\n", err)
+	}
+}
+
+// ask the rotation period until it is valid
+func askMaxAge(scanner *bufio.Scanner, prompt string) string {
+	for {
+		fmt.Print(prompt)
+		scanner.Scan()
+		value := strings.TrimSpace(scanner.Text())
+		if value == "-" {
+			return value
+		}
+		if _, err := secret.ParseMaxAge(value); err != nil {
+			fmt.Printf("Invalid MaxAge: %v\n", err)
+			continue
+		}
+		return value
+	}
+}
+
+// return ******** for a value that is set
+func maskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "********"
+}
+
+// read lines until a line with a single "." or the end of the input, an empty first line return ""
+func readMultiline(scanner *bufio.Scanner) string {
+	var lines []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "." || (line == "" && len(lines) == 0) {
+			break
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
 // read user input for smartcard pin
 func ReadPin() string {
 	fmt.Print("Enter PIN: ")
@@ -135,6 +307,14 @@ func ReadPin() string {
 	return pin
 }
 
+// read the passphrase unlocking the local store
+func ReadPassphrase() string {
+	fmt.Print("Enter Passphrase: ")
+	var passphrase string
+	fmt.Scanln(&passphrase)
+	return passphrase
+}
+
 // ask for username and password and return them
 func ReadUsernamePassword() (string, string) {
 	fmt.Print("Enter Username: ")
@@ -172,9 +352,13 @@ func AddSecretInteractive(ctx context.Context, secstore securestore.SecretStore)
 		}
 	}
 	//ask the Secret detail
-	fieldValues := AskUser()
+	fieldValues := AskUser(ctx, secstore)
 	//convet to secret
 	newSecret, _ := secret.ConvertToSecret(convertMap(fieldValues))
+	//check the credential against the breached passwords
+	if err := checkBreached(newSecret.Credential); err != nil {
+		return err
+	}
 	err := securestore.AddSecret(ctx, secstore, newSecret, secretID)
 	return err
 
@@ -182,47 +366,77 @@ func AddSecretInteractive(ctx context.Context, secstore securestore.SecretStore)
 
 // this function will ask the user to enter the secret id and it will search it in vault and allow update it if the secret did not expire
 func UpdateSecretInteractive(ctx context.Context, secstore securestore.SecretStore) error {
-
 	//read the secret id from the user
-	secretID := AskSecret()
+	return UpdateSecretIDInteractive(ctx, secstore, AskSecret())
+}
 
+// this function will allow the user to update the given secret id
+func UpdateSecretIDInteractive(ctx context.Context, secstore securestore.SecretStore, secretID string) error {
 	//	currentSecret := secret.ConvertFromSecret(securestore.GetSecret(ctx, secstore, secretID))
 
-	sec, err := securestore.GetSecret(ctx, secstore, secretID)
+	sec, version, err := securestore.GetSecretVersion(ctx, secstore, secretID)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//convert secret to map[string]interface{}
-	currentSecret := secret.ConvertFromSecret(sec)
-
-	//read the secret from the user
-	fieldNames := SecretHumanFieldNames
-	fieldValues := make(map[string]string)
-	for _, fieldName := range fieldNames {
-		if fieldValue, ok := currentSecret[fieldName]; ok {
-			fieldValues[fieldName] = fieldValue.(string)
-		} else {
-			fieldValues[fieldName] = "" // Default to empty string if the field is not present
+	//read the current values of the fields of the secret type
+	fieldValues := map[string]string{"Type": sec.Type, "Tags": strings.Join(sec.Tags, ",")}
+	for _, fieldName := range secret.HumanFieldNames(sec.Type) {
+		if fieldName != "Tags" {
+			fieldValues[fieldName] = sec.Field(fieldName)
 		}
 	}
 
-	newValue := AskUserwithPrevious(fieldValues)
+	if !secstore.Backend.Versioned() {
+		fmt.Println("Warning: no conflict detection on this storage, changes made meanwhile by others will be overwritten")
+	}
+	newValue := AskUserwithPrevious(ctx, secstore, fieldValues)
 	//fmt.Printf("newValue: %v\n", newValue)
 	//convert to secret
 	newSecret, _ := secret.ConvertToSecret(convertMap(newValue))
+	//the attachments are not asked, they are kept as is
+	newSecret.Attachments = sec.Attachments
+	//a new credential is checked against the breached passwords
+	if newSecret.Credential != sec.Credential {
+		if err := checkBreached(newSecret.Credential); err != nil {
+			return err
+		}
+	}
 	//fmt.Printf("newSecret: %v\n", newSecret)
-	securestore.AddSecret(ctx, secstore, newSecret, secretID)
-	if err != nil {
-		log.Fatal(err)
+	for {
+		err = securestore.UpdateSecret(ctx, secstore, newSecret, secretID, version)
+		if !securestore.IsConflict(err) {
+			return err
+		}
+		//someone else updated the secret since it was read
+		fmt.Println(err)
+		remote, remoteVersion, rErr := securestore.GetSecretVersion(ctx, secstore, secretID)
+		if rErr != nil {
+			return rErr
+		}
+		displayChanges(sec, remote, newSecret)
+		switch askConflictAction() {
+		case "m":
+			newSecret = mergeSecret(sec, newSecret, remote)
+		case "o":
+			//keep the user version as is
+		default:
+			fmt.Println("Update aborted")
+			return err
+		}
+		sec, version = remote, remoteVersion
 	}
-	return err
 }
 
 // this function will ask the user an ID and it will delete it from vault
 func DeleteSecretInteractive(ctx context.Context, secstore securestore.SecretStore) error {
-	//delete the secret
-	err := securestore.DeleteSecret(ctx, secstore, AskSecretI(DefaultInteractif{}))
-
+	//delete the secret, it is moved to the trash
+	secretID := AskSecretI(DefaultInteractif{})
+	err := securestore.DeleteSecret(ctx, secstore, secretID)
+	if err == nil && secstore.Backend.Versioned() {
+		fmt.Printf("Secret ID: %s moved to the trash\n", secretID)
+	} else if err == nil {
+		fmt.Printf("Secret ID: %s permanently deleted\n", secretID)
+	}
 	return err
 }
 
@@ -12,9 +12,24 @@ import (
 	"github.com/google/uuid"
 )
 
-// return random password after asking the user for the length and complexity
-func RandomPassword() {
+// the passphrase used by the ** shortcut
+var defaultPassphrase = crypto.PassphraseOptions{Words: crypto.PassphraseWords, Separator: "-", Capitalize: true, Digit: true}
+
+// return random password after asking the user for the length and complexity, a password of a policy
+// or a diceware passphrase after asking the number of words, the separator, the capitalisation and the digit
+func RandomPassword(ctx context.Context, secstore securestore.SecretStore) {
 	fmt.Println("Random Password")
+	fmt.Print("Password, Passphrase or Policy (w=password, p=passphrase, n=policy): ")
+	var kind string
+	fmt.Scanln(&kind)
+	switch {
+	case strings.HasPrefix(strings.ToLower(kind), "p"):
+		RandomPassphrase()
+		return
+	case strings.HasPrefix(strings.ToLower(kind), "n"):
+		RandomPolicyPassword(ctx, secstore)
+		return
+	}
 	fmt.Print("Enter Password Length: ")
 	var length int
 	fmt.Scanln(&length)
@@ -42,6 +57,40 @@ func RandomPassword() {
 	fmt.Printf("Password: %s\n", crypto.RandomPassword(length, strings.Contains(complexity, "l"), strings.Contains(complexity, "u"), strings.Contains(complexity, "d"), strings.Contains(complexity, "s"), special))
 }
 
+// return a random diceware passphrase and its entropy after asking the user for the options
+func RandomPassphrase() {
+	options := defaultPassphrase
+	fmt.Print("Enter Number of Words: ")
+	fmt.Scanln(&options.Words)
+	if options.Words <= 0 {
+		options.Words = defaultPassphrase.Words
+		fmt.Printf("Number of Words: %d\n", options.Words)
+	}
+	fmt.Printf("Enter Separator (%q, space for a space): ", options.Separator)
+	var separator string
+	fmt.Scanln(&separator)
+	if separator == "space" {
+		options.Separator = " "
+	} else if separator != "" {
+		options.Separator = separator
+	}
+	fmt.Print("Capitalize the words (Y/n): ")
+	var answer string
+	fmt.Scanln(&answer)
+	options.Capitalize = !strings.EqualFold(answer, "n")
+	answer = ""
+	fmt.Print("Insert a digit (Y/n): ")
+	fmt.Scanln(&answer)
+	options.Digit = !strings.EqualFold(answer, "n")
+	passphrase, entropy, err := crypto.RandomPassphrase(options)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	fmt.Printf("Passphrase: %s\n", passphrase)
+	fmt.Printf("Entropy: %.1f bits\n", entropy)
+}
+
 // this function will create a temporary token that will return the secret once unwrapped
 func GenerateBootstrapToken(ctx context.Context, secstore securestore.SecretStore) {
 	fmt.Println("Generate bootstrap token")
@@ -76,7 +125,7 @@ func GenerateBootstrapTokenList(ctx context.Context, secstore securestore.Secret
 		fmt.Printf("Token TTL: %d\n", ttl)
 	}
 	//select the SecretId
-	fmt.Print("Enter Secret ID CSV list: ")
+	fmt.Print("Enter Secret ID CSV list (a folder ends with /): ")
 	var secretID string
 	fmt.Scanln(&secretID)
 	//generate uuid string
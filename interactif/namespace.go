@@ -0,0 +1,31 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this function will ask the user a namespace and return a SecretStore using it
+// the current token is kept, it must be allowed in the new namespace
+func SwitchNamespaceInteractive(ctx context.Context, secstore securestore.SecretStore) (securestore.SecretStore, error) {
+	if secstore.Namespace == "" {
+		fmt.Println("Current namespace: root")
+	} else {
+		fmt.Printf("Current namespace: %s\n", secstore.Namespace)
+	}
+	fmt.Print("Enter Namespace (empty for root): ")
+	var namespace string
+	fmt.Scanln(&namespace)
+	switched, err := securestore.SwitchNamespace(ctx, secstore, namespace)
+	if err != nil {
+		return secstore, err
+	}
+	if switched.Namespace == "" {
+		fmt.Println("Switched to the root namespace")
+	} else {
+		fmt.Printf("Switched to namespace: %s\n", switched.Namespace)
+	}
+	return switched, nil
+}
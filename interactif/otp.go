@@ -0,0 +1,33 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abruno06/myvault/crypto"
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this function will ask a secret ID and display its current TOTP code and the seconds it remains valid
+func OTPCodeInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	secretID := AskSecret()
+	sec, err := securestore.GetSecret(ctx, secstore, secretID)
+	if err != nil {
+		return err
+	}
+	if sec.OTP == "" {
+		return fmt.Errorf("Secret ID: %s has no OTP", secretID)
+	}
+	totp, err := crypto.ParseTOTP(sec.OTP)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	code, err := totp.Code(now)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Code: %s (valid %d more seconds)\n", code, totp.Remaining(now))
+	return nil
+}
@@ -0,0 +1,31 @@
+package interactif
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/abruno06/myvault/config"
+)
+
+// this function will display the profiles of config.json and ask the user the one to use
+func SwitchProfileInteractive() error {
+	profiles := config.ListProfiles()
+	if len(profiles) == 0 {
+		return errors.New("no PROFILES in config.json")
+	}
+	fmt.Println("Profiles:")
+	for _, name := range profiles {
+		if name == config.ReadProfile() {
+			fmt.Printf("* %s\n", name)
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	fmt.Print("Enter Profile: ")
+	var name string
+	fmt.Scanln(&name)
+	if name == "" {
+		return errors.New("no profile selected")
+	}
+	return config.SetProfile(name)
+}
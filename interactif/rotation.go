@@ -0,0 +1,24 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this function will display the secrets overdue or due soon for rotation
+// quiet display nothing when no secret is due (used after login)
+func RotationReportInteractive(ctx context.Context, secstore securestore.SecretStore, quiet bool) error {
+	entries, err := securestore.RotationReport(ctx, secstore, securestore.ReadRotationPolicy(), time.Now())
+	if err != nil {
+		return err
+	}
+	if quiet && len(entries) == 0 {
+		return nil
+	}
+	fmt.Println("Secrets due for rotation")
+	securestore.DisplayRotationReport(entries)
+	return nil
+}
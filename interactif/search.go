@@ -0,0 +1,77 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/abruno06/myvault/securestore"
+)
+
+// the number of results displayed by the search
+const SearchMaxResults = 20
+
+// display the search results with a number to select them
+func displaySearchResults(results []securestore.SearchResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	format := "%s\t%s\t%s\t%s\t%s\n"
+	fmt.Fprintf(w, format, "No", "ID", "Username", "URL", "Match")
+	for i, r := range results {
+		fmt.Fprintf(w, format, fmt.Sprint(i+1), r.SecretID, r.Secret.Summary(), r.Secret.URL, r.Field)
+	}
+	w.Flush()
+}
+
+// ask the user what to do with the selected secret: show, copy or update
+func askSearchAction(readOnly bool) string {
+	if readOnly {
+		fmt.Print("(s)how, (c)opy credential or (a)bort: ")
+	} else {
+		fmt.Print("(s)how, (c)opy credential, (u)pdate or (a)bort: ")
+	}
+	var action string
+	fmt.Scanln(&action)
+	return action
+}
+
+// this function will ask the user a query, display the matching secrets and act on the one selected
+func SearchInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	fmt.Print("Enter Search: ")
+	reader := DefaultInteractif{}
+	query := reader.ReadLine()
+	results, err := securestore.SearchSecrets(ctx, secstore, query)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Printf("No secret matching %q\n", query)
+		return nil
+	}
+	if len(results) > SearchMaxResults {
+		fmt.Printf("%d secrets found, showing the first %d\n", len(results), SearchMaxResults)
+		results = results[:SearchMaxResults]
+	}
+	displaySearchResults(results)
+	fmt.Print("Select No (empty to abort): ")
+	var choice int
+	fmt.Scanln(&choice)
+	if choice < 1 || choice > len(results) {
+		return nil
+	}
+	selected := results[choice-1]
+	readOnly := securestore.OfflineBanner(secstore) != ""
+	switch action := askSearchAction(readOnly); {
+	case action == "s":
+		fmt.Printf("Secret ID: %s\n%s", selected.SecretID, selected.Secret)
+	case action == "c":
+		if err := CopyToClipboard(selected.Secret.MainSecret()); err != nil {
+			return err
+		}
+		fmt.Printf("Secret of %s copied to the clipboard\n", selected.SecretID)
+	case action == "u" && !readOnly:
+		//the offline cache can not be updated, "u" is ignored there
+		return UpdateSecretIDInteractive(ctx, secstore, selected.SecretID)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/abruno06/myvault/securestore"
+	"github.com/google/uuid"
+)
+
+// display the tags in use and ask the user one of them
+func askTag(ctx context.Context, secstore securestore.SecretStore) (string, error) {
+	tags, err := securestore.ListTags(ctx, secstore)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+	fmt.Println("Tags:")
+	for _, tag := range names {
+		fmt.Printf("  %s (%d)\n", tag, tags[tag])
+	}
+	fmt.Print("Enter Tag: ")
+	var tag string
+	fmt.Scanln(&tag)
+	return tag, nil
+}
+
+// this function will ask the user a tag and list the secrets carrying it
+func ListTagInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	tag, err := askTag(ctx, secstore)
+	if err != nil {
+		return err
+	}
+	return securestore.ListTag(ctx, secstore, tag)
+}
+
+// this function will create a temporary token that will return every secret carrying a tag once unwrapped
+func GenerateBootstrapTokenTag(ctx context.Context, secstore securestore.SecretStore) {
+	fmt.Println("Generate bootstrap token for a tag")
+	fmt.Print("Enter Token TTL (in minutes): ")
+	var ttl int
+	fmt.Scanln(&ttl)
+	//if empty use default
+	if ttl == 0 {
+		ttl = 24
+		fmt.Printf("Token TTL: %d\n", ttl)
+	}
+	tag, err := askTag(ctx, secstore)
+	if err != nil {
+		log.Fatal(err)
+	}
+	uuid := uuid.New().String()
+	wToken, err := securestore.WrapSecretTag(ctx, secstore, tag, uuid, time.Duration(ttl)*time.Minute)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("Bootstrap Token: %s\n", wToken)
+}
@@ -45,7 +45,8 @@ func GenerateServiceToken(ctx context.Context, secstore securestore.SecretStore)
 	//fmt.Printf("Bootstrap Secret Token: %s\n", wSecretToken)
 
 	//build a token securestore to store the secrets in the cubbyhole
-	newSecstore, err := securestore.ConnectVaultWithToken(ctx, serviceToken)
+	//the service token belong to the namespace of the current session
+	newSecstore, err := securestore.ConnectVaultWithTokenNamespace(ctx, serviceToken, secstore.Namespace)
 	if err != nil {
 		log.Fatalf("Error connecting to Vault using service token: %v\n", err)
 
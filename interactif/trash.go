@@ -0,0 +1,63 @@
+package interactif
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/abruno06/myvault/securestore"
+)
+
+// this function will ask the user a secret ID from the trash and restore it
+func RestoreTrashInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	if err := securestore.DisplayTrash(ctx, secstore); err != nil {
+		return err
+	}
+	secretID := AskSecret()
+	err := securestore.UndeleteSecret(ctx, secstore, secretID)
+	if err == nil {
+		fmt.Printf("Secret ID: %s restored\n", secretID)
+	}
+	return err
+}
+
+// this function will ask the user a secret ID from the trash (or "expired" for all the expired entries)
+// and permanently remove it after confirmation
+func PurgeTrashInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	if err := securestore.DisplayTrash(ctx, secstore); err != nil {
+		return err
+	}
+	fmt.Println("Enter expired to purge all the entries whose retention is over")
+	secretID := AskSecret()
+	if secretID == "expired" {
+		return purgeExpiredInteractive(ctx, secstore)
+	}
+	fmt.Printf("Secret ID: %s and all its versions will be destroyed, type yes to confirm: ", secretID)
+	var confirm string
+	fmt.Scanln(&confirm)
+	if strings.ToLower(confirm) != "yes" {
+		fmt.Println("Purge aborted")
+		return nil
+	}
+	err := securestore.PurgeSecret(ctx, secstore, secretID)
+	if err == nil {
+		fmt.Printf("Secret ID: %s purged\n", secretID)
+	}
+	return err
+}
+
+// this function will permanently remove the expired entries of the trash after confirmation
+func purgeExpiredInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	fmt.Print("All the expired secrets and their versions will be destroyed, type yes to confirm: ")
+	var confirm string
+	fmt.Scanln(&confirm)
+	if strings.ToLower(confirm) != "yes" {
+		fmt.Println("Purge aborted")
+		return nil
+	}
+	purged, err := securestore.PurgeExpiredTrash(ctx, secstore)
+	for _, id := range purged {
+		fmt.Printf("Secret ID: %s purged\n", id)
+	}
+	return err
+}
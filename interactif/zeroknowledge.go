@@ -0,0 +1,89 @@
+package interactif
+
+import (
+	"context"
+	gocrypto "crypto"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/securestore"
+	"github.com/abruno06/myvault/smartcard"
+
+	"github.com/go-piv/piv-go/piv"
+)
+
+// this function will unlock the zero knowledge data key of the user and return the store encrypting the secrets with it
+// the keyring is created the first time after confirmation and its recovery code is shown once, the recovery code is
+// asked when the Yubikey is missing or does not unlock the keyring, a keyring that does not verify is refused;
+// pin is the pin already typed by the user, it is asked if empty
+func UnlockZeroKnowledgeInteractive(ctx context.Context, secstore securestore.SecretStore, yk *piv.YubiKey, pin string) (securestore.SecretStore, error) {
+	key, err := keyManagementYubikey(yk, "unlock the zero knowledge keyring")
+	if err != nil {
+		fmt.Println(err)
+		return recoverZeroKnowledge(ctx, secstore, nil)
+	}
+	if key != yk {
+		defer key.Close()
+	}
+	if pin == "" {
+		pin = ReadPin()
+	}
+	pub, priv, err := smartcard.KeyManagementKey(key, pin)
+	if err != nil {
+		return secstore, err
+	}
+	dataKey, err := securestore.UnlockKeyring(ctx, secstore, config.User, priv)
+	if errors.Is(err, securestore.ErrKeyringNotFound) {
+		fmt.Printf("No zero knowledge keyring found for %s, type yes to create it: ", config.User)
+		var confirm string
+		fmt.Scanln(&confirm)
+		if strings.ToLower(confirm) != "yes" {
+			return secstore, err
+		}
+		var code string
+		if dataKey, code, err = securestore.InitKeyring(ctx, secstore, config.User, pub); err != nil {
+			return secstore, err
+		}
+		fmt.Printf("Zero knowledge keyring created for %s\n", config.User)
+		fmt.Printf("Recovery code: %s\n", code)
+		fmt.Println("Write it down and keep it offline, it is the only way to read the secrets without this Yubikey")
+	} else if errors.Is(err, securestore.ErrKeyringNotVerified) {
+		return secstore, err
+	} else if err != nil {
+		fmt.Printf("This Yubikey does not unlock the keyring of %s: %v\n", config.User, err)
+		return recoverZeroKnowledge(ctx, secstore, pub)
+	}
+	return securestore.SealedStore(secstore, dataKey), nil
+}
+
+// unlock the data key with the recovery code, the keyring is wrapped to pub (the Yubikey plugged in) if it is set
+func recoverZeroKnowledge(ctx context.Context, secstore securestore.SecretStore, pub gocrypto.PublicKey) (securestore.SecretStore, error) {
+	fmt.Print("Enter Recovery Code: ")
+	var code string
+	fmt.Scanln(&code)
+	dataKey, err := securestore.RecoverKeyring(ctx, secstore, config.User, code, pub)
+	if err != nil {
+		return secstore, err
+	}
+	if pub != nil {
+		fmt.Println("The keyring is now unlocked by this Yubikey")
+	}
+	return securestore.SealedStore(secstore, dataKey), nil
+}
+
+// this function will encrypt the secrets written before the zero knowledge encryption was enabled after confirmation,
+// their older plaintext versions are destroyed
+func SealExistingInteractive(ctx context.Context, secstore securestore.SecretStore) error {
+	fmt.Print("The older versions of the secrets holding plaintext will be destroyed, type yes to confirm: ")
+	var confirm string
+	fmt.Scanln(&confirm)
+	if strings.ToLower(confirm) != "yes" {
+		fmt.Println("Encryption aborted")
+		return nil
+	}
+	count, err := securestore.SealExisting(ctx, secstore)
+	fmt.Printf("%d entrie(s) encrypted\n", count)
+	return err
+}
@@ -0,0 +1,56 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Attachment is a file stored with a secret, a small file is kept in Data (base64)
+// the content of a large file is split in Chunks entries stored apart from the secret (see securestore)
+type Attachment struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Data   string `json:"data,omitempty"`
+	// ChunkID name the entries holding the content when it is not in Data
+	ChunkID string `json:"chunkid,omitempty"`
+	Chunks  int    `json:"chunks,omitempty"`
+}
+
+// return the attachment with the given name, false if the secret has none
+func (s Secret) Attachment(name string) (Attachment, bool) {
+	for _, a := range s.Attachments {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// the attachments are stored as a JSON string, it keeps the entry flat for every backend
+func encodeAttachments(attachments []Attachment) string {
+	data, _ := json.Marshal(attachments)
+	return string(data)
+}
+
+// read the attachments stored by encodeAttachments, an invalid value has no attachment
+func decodeAttachments(value string) []Attachment {
+	var rValue []Attachment
+	if err := json.Unmarshal([]byte(value), &rValue); err != nil {
+		return nil
+	}
+	return rValue
+}
+
+// return the Attachments line of String, empty when the secret has no attachment
+func (s Secret) attachmentsString() string {
+	if len(s.Attachments) == 0 {
+		return ""
+	}
+	var names []string
+	for _, a := range s.Attachments {
+		names = append(names, fmt.Sprintf("%s (%d bytes)", a.Name, a.Size))
+	}
+	return fmt.Sprintf("Attachments: %s\n", strings.Join(names, ", "))
+}
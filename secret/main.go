@@ -2,6 +2,8 @@ package secret
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -13,14 +15,79 @@ type Secret struct {
 	Comment      string    `json:"comment"`
 	LastUpdate   time.Time `json:"lastupdate"`
 	LastUpdateBy string    `json:"lastupdateby"`
+	Tags         []string  `json:"tags,omitempty"` //optional
+	// Type select the fields of the secret, empty is a login (see SecretTypes)
+	Type   string            `json:"type,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+	// OTP is the otpauth:// URI of the TOTP seed (optional)
+	OTP string `json:"otp,omitempty"`
+	// files stored with the secret (optional)
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// MaxAge is the rotation period of the secret ("90d"), empty use the tag or application default
+	MaxAge string `json:"maxage,omitempty"`
 }
 
-var SecretFieldNames = []string{"Username", "Credential", "URL", "LastUpdate", "LastUpdateBy", "Comment"}
-var SecretHumanFieldNames = []string{"Username", "Credential", "URL", "Comment"}
+var SecretFieldNames = []string{"Username", "Credential", "URL", "LastUpdate", "LastUpdateBy", "Comment", "Tags", "OTP", "MaxAge"}
+var SecretHumanFieldNames = []string{"Username", "Credential", "URL", "Comment", "Tags", "OTP", "MaxAge"}
 
 // String method for the Secret struct
 func (s Secret) String() string {
-	return fmt.Sprintf("Username: %s\nCredential: %s\nURL: %s\nComment: %s\nLastUpdate: %s\nLastUpdateBy: %s\n", s.Username, s.Credential, s.URL, s.Comment, s.LastUpdate, s.LastUpdateBy)
+	if s.TypeName() != TypeLogin {
+		return s.typedString()
+	}
+	return fmt.Sprintf("Username: %s\nCredential: %s\nURL: %s\nComment: %s\nTags: %s\nLastUpdate: %s\nLastUpdateBy: %s\n", s.Username, s.Credential, s.URL, s.Comment, strings.Join(s.Tags, ", "), s.LastUpdate, s.LastUpdateBy) + s.optionalString()
+}
+
+// compare two secrets field by field
+func (s Secret) Equal(other Secret) bool {
+	if len(s.Tags) != len(other.Tags) || s.TypeName() != other.TypeName() || len(s.Fields) != len(other.Fields) || len(s.Attachments) != len(other.Attachments) {
+		return false
+	}
+	for i := range s.Attachments {
+		if s.Attachments[i] != other.Attachments[i] {
+			return false
+		}
+	}
+	for k, v := range s.Fields {
+		if other.Fields[k] != v {
+			return false
+		}
+	}
+	for i := range s.Tags {
+		if s.Tags[i] != other.Tags[i] {
+			return false
+		}
+	}
+	return s.Username == other.Username && s.Credential == other.Credential && s.URL == other.URL &&
+		s.Comment == other.Comment && s.LastUpdate.Equal(other.LastUpdate) && s.LastUpdateBy == other.LastUpdateBy && s.OTP == other.OTP && s.MaxAge == other.MaxAge
+}
+
+// check if the secret carry the tag
+func (s Secret) HasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// convert a comma separated list of tags to a tag list
+// the tags are trimmed, in lower case, sorted and without duplicates
+func ParseTags(value string) []string {
+	seen := make(map[string]bool)
+	var rValue []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		rValue = append(rValue, tag)
+	}
+	sort.Strings(rValue)
+	return rValue
 }
 
 // convert the map[string]interface {}  to Secret
@@ -56,6 +123,35 @@ func ConvertToSecret(object map[string]interface{}) (Secret, bool) {
 		}
 	}
 	rValue.LastUpdateBy, _ = object["LastUpdateBy"].(string)
+	//tags are optional, stored as a comma separated string or as a list
+	switch tags := object["Tags"].(type) {
+	case string:
+		rValue.Tags = ParseTags(tags)
+	case []interface{}:
+		var list []string
+		for _, tag := range tags {
+			if t, isString := tag.(string); isString {
+				list = append(list, t)
+			}
+		}
+		rValue.Tags = ParseTags(strings.Join(list, ","))
+	}
+	rValue.OTP, _ = object["OTP"].(string)
+	rValue.MaxAge, _ = object["MaxAge"].(string)
+	if attachments, isString := object["Attachments"].(string); isString {
+		rValue.Attachments = decodeAttachments(attachments)
+	}
+	//the fields of the type that are not in the struct
+	if typeName, _ := object["Type"].(string); typeName != "" && typeName != TypeLogin {
+		rValue.Type = typeName
+		for key, value := range object {
+			text, isString := value.(string)
+			if !isString || text == "" || isBaseField(key) || key == "Type" || key == "Tags" || key == "Attachments" || key == "LastUpdate" || key == "LastUpdateBy" {
+				continue
+			}
+			rValue.SetField(key, text)
+		}
+	}
 	return rValue, ok
 }
 
@@ -68,6 +164,29 @@ func ConvertFromSecret(secret Secret) map[string]interface{} {
 	rValue["URL"] = secret.URL
 	rValue["LastUpdate"] = secret.LastUpdate.UTC().Format("2006-01-02 15:04:05")
 	rValue["LastUpdateBy"] = secret.LastUpdateBy
+	//untagged secrets are stored without the Tags field
+	if len(secret.Tags) > 0 {
+		rValue["Tags"] = strings.Join(secret.Tags, ",")
+	}
+	//the TOTP seed is optional
+	if secret.OTP != "" {
+		rValue["OTP"] = secret.OTP
+	}
+	//the rotation period is optional
+	if secret.MaxAge != "" {
+		rValue["MaxAge"] = secret.MaxAge
+	}
+	//secrets without attachment are stored without the Attachments field
+	if len(secret.Attachments) > 0 {
+		rValue["Attachments"] = encodeAttachments(secret.Attachments)
+	}
+	//a login is stored without the Type field
+	if secret.TypeName() != TypeLogin {
+		rValue["Type"] = secret.Type
+		for key, value := range secret.Fields {
+			rValue[key] = value
+		}
+	}
 	return rValue
 }
 
@@ -89,3 +208,45 @@ func compareMaps(map1 map[string]interface{}, map2 map[string]interface{}) bool
 	}
 	return true
 }
+
+// FieldChange describe the change of one field between two secrets
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// this function will return the fields that differ between the old and the new secret
+// the credential values are masked unless showCredential is true
+func Diff(oldSecret, newSecret Secret, showCredential bool) []FieldChange {
+	oldMap := ConvertFromSecret(oldSecret)
+	newMap := ConvertFromSecret(newSecret)
+	var rValue []FieldChange
+	//the fields of the new type follow the common fields
+	fields := append([]string{"Type"}, SecretFieldNames...)
+	for _, f := range newSecret.TypeFields() {
+		if !isBaseField(f.Name) {
+			fields = append(fields, f.Name)
+		}
+	}
+	for _, f := range oldSecret.TypeFields() {
+		if !isBaseField(f.Name) && newSecret.Field(f.Name) == "" {
+			fields = append(fields, f.Name)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if seen[field] || oldMap[field] == newMap[field] {
+			continue
+		}
+		seen[field] = true
+		oldValue, _ := oldMap[field].(string)
+		newValue, _ := newMap[field].(string)
+		change := FieldChange{Field: field, Old: oldValue, New: newValue}
+		if (IsSecretField(oldSecret.Type, field) || IsSecretField(newSecret.Type, field)) && !showCredential {
+			change.Old, change.New = "********", "********"
+		}
+		rValue = append(rValue, change)
+	}
+	return rValue
+}
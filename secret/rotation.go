@@ -0,0 +1,33 @@
+package secret
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// this function will read a max age: a number of days ("90d") or a Go duration ("720h"), "" is no max age
+func ParseMaxAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	var age time.Duration
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("max age %q is not valid", value)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("max age %q is not valid", value)
+		}
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("max age %q must be positive", value)
+	}
+	return age, nil
+}
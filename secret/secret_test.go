@@ -10,12 +10,12 @@ import (
 // test the convertToSecret function
 func TestConvertToSecret(t *testing.T) {
 	//test if the conversion is successful
-	if obj, _ := ConvertToSecret(map[string]interface{}{"Username": "user", "Credential": "password", "URL": "url", "Comment": "comment", "LastUpdate": "2020-01-01T00:00:00.000000-07:00", "LastUpdateBy": "user"}); obj == (Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdate: time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC), LastUpdateBy: "user"}) {
-		t.Errorf("ConvertToSecret() = %t; want true", obj == (Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdate: time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC), LastUpdateBy: "user"}))
+	if obj, _ := ConvertToSecret(map[string]interface{}{"Username": "user", "Credential": "password", "URL": "url", "Comment": "comment", "LastUpdate": "2020-01-01T00:00:00.000000-07:00", "LastUpdateBy": "user"}); obj.Equal(Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdate: time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC), LastUpdateBy: "user"}) {
+		t.Errorf("ConvertToSecret() = %t; want true", obj.Equal(Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdate: time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC), LastUpdateBy: "user"}))
 	}
 	//test if the conversion is not successful
-	if obj, _ := ConvertToSecret(map[string]interface{}{"Username": "user", "Credential": "password", "URL": "url", "Comment": "comment", "LastUpdate": "2020-01-01T00:00:00.000000-07:00", "LastUpdateBy": "user1"}); obj == (Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdate: time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC), LastUpdateBy: "user"}) {
-		t.Errorf("ConvertToSecret() = %t; want false", obj == (Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdate: time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC), LastUpdateBy: "user"}))
+	if obj, _ := ConvertToSecret(map[string]interface{}{"Username": "user", "Credential": "password", "URL": "url", "Comment": "comment", "LastUpdate": "2020-01-01T00:00:00.000000-07:00", "LastUpdateBy": "user1"}); obj.Equal(Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdate: time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC), LastUpdateBy: "user"}) {
+		t.Errorf("ConvertToSecret() = %t; want false", obj.Equal(Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdate: time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC), LastUpdateBy: "user"}))
 	}
 }
 
@@ -106,3 +106,104 @@ func TestCompareMaps(t *testing.T) {
 		t.Errorf("compareMaps() = %t; want false", compareMaps(mysecretmap, mysecretmapfalse2))
 	}
 }
+
+// test the Diff function
+func TestDiff(t *testing.T) {
+	oldSecret := Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdateBy: "user"}
+	newSecret := Secret{Username: "user", Credential: "password2", URL: "url", Comment: "new comment", LastUpdateBy: "user"}
+	changes := Diff(oldSecret, newSecret, false)
+	if len(changes) != 2 {
+		t.Fatalf("Diff() = %v; want 2 changes", changes)
+	}
+	if changes[0].Field != "Credential" || changes[0].Old != "********" || changes[0].New != "********" {
+		t.Errorf("Diff() = %v; want masked Credential change", changes[0])
+	}
+	if changes[1].Field != "Comment" || changes[1].Old != "comment" || changes[1].New != "new comment" {
+		t.Errorf("Diff() = %v; want Comment change", changes[1])
+	}
+	if changes := Diff(oldSecret, newSecret, true); changes[0].New != "password2" {
+		t.Errorf("Diff() = %v; want clear Credential", changes[0])
+	}
+	if changes := Diff(oldSecret, oldSecret, false); len(changes) != 0 {
+		t.Errorf("Diff() = %v; want no change", changes)
+	}
+}
+
+// test the tags are normalized and kept by the conversions
+func TestTags(t *testing.T) {
+	tags := ParseTags(" Prod, db,,prod ")
+	if len(tags) != 2 || tags[0] != "db" || tags[1] != "prod" {
+		t.Errorf("ParseTags() = %v; want [db prod]", tags)
+	}
+	mysecret := Secret{Username: "user", Credential: "password", Tags: tags}
+	converted, ok := ConvertToSecret(ConvertFromSecret(mysecret))
+	if !ok || !converted.Equal(mysecret) || !converted.HasTag("PROD") {
+		t.Errorf("ConvertToSecret(ConvertFromSecret()) = %v; want %v", converted, mysecret)
+	}
+	if converted, _ := ConvertToSecret(map[string]interface{}{"Username": "", "Credential": "", "URL": "", "Comment": "", "Tags": []interface{}{"b", "a"}}); len(converted.Tags) != 2 || converted.Tags[0] != "a" {
+		t.Errorf("ConvertToSecret() with a tag list = %v; want [a b]", converted.Tags)
+	}
+	if _, ok := ConvertFromSecret(Secret{})["Tags"]; ok {
+		t.Errorf("ConvertFromSecret() of an untagged secret has a Tags field")
+	}
+}
+
+// test the typed secrets
+func TestTypes(t *testing.T) {
+	key := Secret{Type: "ssh", Username: "deploy", Comment: "build", Fields: map[string]string{"PrivateKey": "-----BEGIN KEY-----\nabc\n-----END KEY-----\n", "Host": "build.local"}}
+	converted, ok := ConvertToSecret(ConvertFromSecret(key))
+	if !ok || !converted.Equal(key) {
+		t.Errorf("ConvertToSecret(ConvertFromSecret()) = %v; want %v", converted, key)
+	}
+	if converted.MainSecret() != key.Fields["PrivateKey"] || converted.Summary() != "deploy" {
+		t.Errorf("MainSecret() = %q, Summary() = %q; want the private key and deploy", converted.MainSecret(), converted.Summary())
+	}
+	if _, ok := ConvertFromSecret(Secret{Username: "user"})["Type"]; ok {
+		t.Errorf("ConvertFromSecret() of a login has a Type field")
+	}
+	if login := (Secret{Credential: "password"}); login.MainSecret() != "password" || login.Equal(Secret{Type: "note", Credential: "password"}) {
+		t.Errorf("a login and a note with the same credential are equal")
+	}
+	changes := Diff(key, Secret{Type: "ssh", Username: "deploy", Comment: "build", Fields: map[string]string{"PrivateKey": "new", "Host": "build.local"}}, false)
+	if len(changes) != 1 || changes[0].Field != "PrivateKey" || changes[0].New != "********" {
+		t.Errorf("Diff() = %v; want the masked PrivateKey", changes)
+	}
+	if fields := HumanFieldNames("note"); len(fields) != 5 || fields[0] != "Note" || !IsMultilineField("note", "Note") {
+		t.Errorf("HumanFieldNames(note) = %v; want [Note Comment Tags OTP MaxAge]", fields)
+	}
+}
+
+// test the TOTP seed is stored only when set and masked in the diffs
+func TestOTP(t *testing.T) {
+	mysecret := Secret{Username: "user", Credential: "password", OTP: "otpauth://totp/ACME:user?secret=GEZDGNBV"}
+	converted, ok := ConvertToSecret(ConvertFromSecret(mysecret))
+	if !ok || !converted.Equal(mysecret) {
+		t.Errorf("ConvertToSecret(ConvertFromSecret()) = %v; want %v", converted, mysecret)
+	}
+	if _, ok := ConvertFromSecret(Secret{})["OTP"]; ok {
+		t.Errorf("ConvertFromSecret() of a secret without OTP has an OTP field")
+	}
+	if changes := Diff(Secret{}, mysecret, false); len(changes) != 3 || changes[2].Field != "OTP" || changes[2].New != "********" {
+		t.Errorf("Diff() = %v; want the masked OTP", changes)
+	}
+}
+
+// test the max age formats
+func TestParseMaxAge(t *testing.T) {
+	var testcases = []struct {
+		value string
+		age   time.Duration
+		valid bool
+	}{
+		{"", 0, true},
+		{"90d", 90 * 24 * time.Hour, true},
+		{"720h", 720 * time.Hour, true},
+		{"0d", 0, false},
+		{"ninety", 0, false},
+	}
+	for _, tc := range testcases {
+		if age, err := ParseMaxAge(tc.value); age != tc.age || (err == nil) != tc.valid {
+			t.Errorf("ParseMaxAge(%q) = %v, %v; want %v", tc.value, age, err, tc.age)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The type of a secret select its fields, a login uses Username, Credential and URL
+// the fields of the other types are kept in Fields (Username and URL stay in the struct), every type has a Comment, Tags, an optional OTP and MaxAge
+
+// the default type, used when Type is empty
+const TypeLogin = "login"
+
+// TypeField describe one field of a secret type
+type TypeField struct {
+	Name string
+	// Secret fields are masked in the diffs and copied by the search
+	Secret bool
+	// Multiline fields are read until a line with a single "."
+	Multiline bool
+}
+
+// SecretType describe the fields of a type of secret
+type SecretType struct {
+	Name        string
+	Description string
+	Fields      []TypeField
+}
+
+// the known secret types, the first secret field of a type is its main secret
+var SecretTypes = []SecretType{
+	{TypeLogin, "username and password", []TypeField{{Name: "Username"}, {Name: "Credential", Secret: true}, {Name: "URL"}}},
+	{"ssh", "SSH key pair", []TypeField{{Name: "Username"}, {Name: "PrivateKey", Secret: true, Multiline: true}, {Name: "Passphrase", Secret: true}, {Name: "PublicKey", Multiline: true}, {Name: "Host"}}},
+	{"apikey", "API key or token", []TypeField{{Name: "APIKey", Secret: true}, {Name: "Scopes"}, {Name: "URL"}}},
+	{"certificate", "X.509 certificate and its private key", []TypeField{{Name: "Certificate", Multiline: true}, {Name: "PrivateKey", Secret: true, Multiline: true}, {Name: "Chain", Multiline: true}}},
+	{"note", "secure note", []TypeField{{Name: "Note", Secret: true, Multiline: true}}},
+	{"card", "payment card", []TypeField{{Name: "Cardholder"}, {Name: "Number", Secret: true}, {Name: "Expiry"}, {Name: "CVV", Secret: true}, {Name: "PIN", Secret: true}}},
+}
+
+// return the type definition, false if the type is unknown
+func LookupType(name string) (SecretType, bool) {
+	if name == "" {
+		name = TypeLogin
+	}
+	for _, t := range SecretTypes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return SecretType{}, false
+}
+
+// return the names of the known types
+func TypeNames() []string {
+	var rValue []string
+	for _, t := range SecretTypes {
+		rValue = append(rValue, t.Name)
+	}
+	return rValue
+}
+
+// return the type of the secret, login when not set
+func (s Secret) TypeName() string {
+	if s.Type == "" {
+		return TypeLogin
+	}
+	return s.Type
+}
+
+// return the fields of the secret type, an unknown type has the fields it carries
+func (s Secret) TypeFields() []TypeField {
+	if t, ok := LookupType(s.Type); ok {
+		return t.Fields
+	}
+	var names []string
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var rValue []TypeField
+	for _, name := range names {
+		rValue = append(rValue, TypeField{Name: name})
+	}
+	return rValue
+}
+
+// the fields kept in the Secret struct, the other fields of a type are kept in Fields
+var baseFieldNames = []string{"Username", "Credential", "URL", "Comment", "OTP", "MaxAge"}
+
+// check if the field is kept in the Secret struct
+func isBaseField(name string) bool {
+	for _, f := range baseFieldNames {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// return the value of a field of the secret
+func (s Secret) Field(name string) string {
+	switch name {
+	case "Username":
+		return s.Username
+	case "Credential":
+		return s.Credential
+	case "URL":
+		return s.URL
+	case "Comment":
+		return s.Comment
+	case "OTP":
+		return s.OTP
+	case "MaxAge":
+		return s.MaxAge
+	}
+	return s.Fields[name]
+}
+
+// set the value of a field of the secret
+func (s *Secret) SetField(name, value string) {
+	switch name {
+	case "Username":
+		s.Username = value
+	case "Credential":
+		s.Credential = value
+	case "URL":
+		s.URL = value
+	case "Comment":
+		s.Comment = value
+	case "OTP":
+		s.OTP = value
+	case "MaxAge":
+		s.MaxAge = value
+	default:
+		if s.Fields == nil {
+			s.Fields = make(map[string]string)
+		}
+		s.Fields[name] = value
+	}
+}
+
+// return the main secret value: the credential of a login or the first secret field of the type
+func (s Secret) MainSecret() string {
+	for _, f := range s.TypeFields() {
+		if f.Secret {
+			return s.Field(f.Name)
+		}
+	}
+	return s.Credential
+}
+
+// return a one line summary of the first field that is not secret (the username of a login)
+func (s Secret) Summary() string {
+	for _, f := range s.TypeFields() {
+		if !f.Secret && !f.Multiline {
+			return s.Field(f.Name)
+		}
+	}
+	return ""
+}
+
+// check if the field is secret for its type, unknown fields are not secret except Credential and OTP
+func IsSecretField(typeName, field string) bool {
+	if t, ok := LookupType(typeName); ok {
+		for _, f := range t.Fields {
+			if f.Name == field {
+				return f.Secret
+			}
+		}
+	}
+	return field == "Credential" || field == "OTP"
+}
+
+// check if the field is multiline for its type
+func IsMultilineField(typeName, field string) bool {
+	if t, ok := LookupType(typeName); ok {
+		for _, f := range t.Fields {
+			if f.Name == field {
+				return f.Multiline
+			}
+		}
+	}
+	return false
+}
+
+// return the fields asked to the user for a type: the type fields, Comment, Tags, OTP and MaxAge
+func HumanFieldNames(typeName string) []string {
+	t, ok := LookupType(typeName)
+	if !ok {
+		return []string{"Comment", "Tags", "OTP", "MaxAge"}
+	}
+	var rValue []string
+	for _, f := range t.Fields {
+		rValue = append(rValue, f.Name)
+	}
+	return append(rValue, "Comment", "Tags", "OTP", "MaxAge")
+}
+
+// return a printable value, multiline values are indented below the field name
+func displayValue(value string) string {
+	if !strings.Contains(value, "\n") {
+		return value
+	}
+	return "\n    " + strings.ReplaceAll(strings.TrimRight(value, "\n"), "\n", "\n    ")
+}
+
+// return the String of a secret that is not a login
+func (s Secret) typedString() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Type: %s\n", s.TypeName())
+	for _, f := range s.TypeFields() {
+		fmt.Fprintf(&b, "%s: %s\n", f.Name, displayValue(s.Field(f.Name)))
+	}
+	fmt.Fprintf(&b, "Comment: %s\nTags: %s\nLastUpdate: %s\nLastUpdateBy: %s\n", s.Comment, strings.Join(s.Tags, ", "), s.LastUpdate, s.LastUpdateBy)
+	return b.String() + s.optionalString()
+}
+
+// return the lines of String for the optional fields that are set
+func (s Secret) optionalString() string {
+	var rValue string
+	if s.OTP != "" {
+		rValue += fmt.Sprintf("OTP: %s\n", s.OTP)
+	}
+	if s.MaxAge != "" {
+		rValue += fmt.Sprintf("MaxAge: %s\n", s.MaxAge)
+	}
+	return rValue + s.attachmentsString()
+}
@@ -0,0 +1,310 @@
+package securestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/secret"
+	"github.com/google/uuid"
+)
+
+// A file up to AttachmentInlineSize is stored in the secret entry, a larger one is split in chunks of
+// AttachmentChunkSize stored at <APPNAME>.attachments/<ChunkID>/<n>, outside the secrets of the application
+const (
+	AttachmentInlineSize = 16 * 1024
+	AttachmentChunkSize  = 256 * 1024
+)
+
+// return the path of a chunk of an attachment
+func attachmentChunkPath(secstore SecretStore, chunkID string, n int) string {
+	return secstore.Appname + ".attachments/" + chunkID + "/" + strconv.Itoa(n)
+}
+
+// check the name of an attachment, it is a file name without folder
+func checkAttachmentName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("attachment name %q is not valid", name)
+	}
+	return nil
+}
+
+// this function will attach the data to the secret under the given name
+// the secret is written at the version read, a *ConflictError is returned if it changed in between
+func AttachFile(ctx context.Context, secstore SecretStore, secretID, name string, data []byte) error {
+	if err := checkAttachmentName(name); err != nil {
+		return err
+	}
+	if max := config.ReadAttachmentMaxSize(); int64(len(data)) > max {
+		return fmt.Errorf("attachment %s is %d bytes, the limit is %d", name, len(data), max)
+	}
+	sec, version, found, err := readSecretVersion(ctx, secstore, secretID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("Secret ID: %s not found", secretID)
+	}
+	if _, exist := sec.Attachment(name); exist {
+		return fmt.Errorf("Secret ID: %s already has an attachment %s", secretID, name)
+	}
+	sum := sha256.Sum256(data)
+	attachment := secret.Attachment{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+	if len(data) <= AttachmentInlineSize {
+		attachment.Data = base64.StdEncoding.EncodeToString(data)
+	} else {
+		attachment.ChunkID = uuid.New().String()
+		for n := 0; n*AttachmentChunkSize < len(data); n++ {
+			chunk := data[n*AttachmentChunkSize : min((n+1)*AttachmentChunkSize, len(data))]
+			_, err := secstore.Backend.Put(ctx, attachmentChunkPath(secstore, attachment.ChunkID, n), map[string]interface{}{"Data": base64.StdEncoding.EncodeToString(chunk)}, 0)
+			attachment.Chunks = n + 1
+			if err != nil {
+				destroyChunks(ctx, secstore, attachment)
+				return err
+			}
+		}
+	}
+	sec.Attachments = append(sec.Attachments, attachment)
+	if err := setSecret(ctx, secstore, secretID, sec, version); err != nil {
+		destroyChunks(ctx, secstore, attachment)
+		return err
+	}
+	return nil
+}
+
+// remove the chunks of an attachment, errors are ignored as the chunks are no longer referenced
+func destroyChunks(ctx context.Context, secstore SecretStore, attachment secret.Attachment) {
+	for n := 0; n < attachment.Chunks; n++ {
+		secstore.Backend.Destroy(ctx, attachmentChunkPath(secstore, attachment.ChunkID, n))
+	}
+}
+
+// return the attachments referenced by the versions of the secret that can be read, each ChunkID once
+// with undelete the deleted versions are undeleted to be read then deleted again
+func versionsAttachments(ctx context.Context, secstore SecretStore, secretID string, undelete bool) ([]secret.Attachment, error) {
+	if !secstore.Backend.Versioned() {
+		sec, found, err := readSecret(ctx, secstore, secretID)
+		if err != nil || !found {
+			return nil, err
+		}
+		return sec.Attachments, nil
+	}
+	versions, err := ListSecretVersions(ctx, secstore, secretID)
+	if err != nil {
+		return nil, err
+	}
+	path := secretPath(secstore, secretID)
+	seen := make(map[string]bool)
+	var rValue []secret.Attachment
+	for _, v := range versions {
+		deleted := v.DeletionTime != ""
+		if v.Destroyed || (deleted && !undelete) {
+			continue
+		}
+		if deleted {
+			if err := secstore.Backend.Undelete(ctx, path, v.Version); err != nil {
+				return nil, err
+			}
+		}
+		data, _, err := secstore.Backend.Get(ctx, path, v.Version)
+		if deleted {
+			if derr := secstore.Backend.Delete(ctx, path, v.Version); err == nil {
+				err = derr
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		sec, ok := secret.ConvertToSecret(data)
+		if !ok {
+			continue
+		}
+		for _, a := range sec.Attachments {
+			if a.ChunkID != "" && !seen[a.ChunkID] {
+				seen[a.ChunkID] = true
+				rValue = append(rValue, a)
+			}
+		}
+	}
+	return rValue, nil
+}
+
+// this function will destroy the chunks of the attachments of a secret permanently removed, the chunks still
+// referenced by a readable version of another secret (MoveSecret copies the attachments) are kept
+func destroyOrphanChunks(ctx context.Context, secstore SecretStore, secretID string, attachments []secret.Attachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	ids, err := listSecretIDs(ctx, secstore, "")
+	if err != nil {
+		return err
+	}
+	referenced := make(map[string]bool)
+	for _, id := range ids {
+		if id == secretID {
+			continue
+		}
+		others, err := versionsAttachments(ctx, secstore, id, false)
+		if err != nil {
+			return err
+		}
+		for _, a := range others {
+			referenced[a.ChunkID] = true
+		}
+	}
+	for _, a := range attachments {
+		if !referenced[a.ChunkID] {
+			destroyChunks(ctx, secstore, a)
+		}
+	}
+	return nil
+}
+
+// this function will return the attachments of the secret
+func ListAttachments(ctx context.Context, secstore SecretStore, secretID string) ([]secret.Attachment, error) {
+	sec, found, err := readSecret(ctx, secstore, secretID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("Secret ID: %s not found", secretID)
+	}
+	return sec.Attachments, nil
+}
+
+// this function display the attachments in tabular format
+func DisplayAttachments(attachments []secret.Attachment) {
+	if len(attachments) == 0 {
+		fmt.Println("No attachment")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	format := "%s\t%s\t%s\n"
+	fmt.Fprintf(w, format, "Name", "Size", "SHA256")
+	for _, a := range attachments {
+		fmt.Fprintf(w, format, a.Name, strconv.FormatInt(a.Size, 10), a.SHA256)
+	}
+	w.Flush()
+}
+
+// this function will return the content of an attachment, its checksum is verified
+func ReadAttachment(ctx context.Context, secstore SecretStore, secretID, name string) ([]byte, error) {
+	sec, found, err := readSecret(ctx, secstore, secretID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("Secret ID: %s not found", secretID)
+	}
+	attachment, exist := sec.Attachment(name)
+	if !exist {
+		return nil, fmt.Errorf("Secret ID: %s has no attachment %s", secretID, name)
+	}
+	var data bytes.Buffer
+	if attachment.ChunkID == "" {
+		decoded, err := base64.StdEncoding.DecodeString(attachment.Data)
+		if err != nil {
+			return nil, err
+		}
+		data.Write(decoded)
+	}
+	for n := 0; n < attachment.Chunks; n++ {
+		entry, _, err := secstore.Backend.Get(ctx, attachmentChunkPath(secstore, attachment.ChunkID, n), 0)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s chunk %d: %w", name, n, err)
+		}
+		encoded, _ := entry["Data"].(string)
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s chunk %d: %w", name, n, err)
+		}
+		data.Write(decoded)
+	}
+	sum := sha256.Sum256(data.Bytes())
+	if hex.EncodeToString(sum[:]) != attachment.SHA256 {
+		return nil, fmt.Errorf("attachment %s is corrupted (checksum mismatch)", name)
+	}
+	return data.Bytes(), nil
+}
+
+// this function will write the attachment to the file, the file is only readable by its owner (0600)
+func ExtractAttachment(ctx context.Context, secstore SecretStore, secretID, name, path string) error {
+	data, err := ReadAttachment(ctx, secstore, secretID, name)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// this function will remove the attachment from the secret and delete its chunks
+// the previous versions of the secret lose the content of a chunked attachment
+func DeleteAttachment(ctx context.Context, secstore SecretStore, secretID, name string) error {
+	sec, version, found, err := readSecretVersion(ctx, secstore, secretID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("Secret ID: %s not found", secretID)
+	}
+	attachment, exist := sec.Attachment(name)
+	if !exist {
+		return fmt.Errorf("Secret ID: %s has no attachment %s", secretID, name)
+	}
+	var kept []secret.Attachment
+	for _, a := range sec.Attachments {
+		if a.Name != name {
+			kept = append(kept, a)
+		}
+	}
+	sec.Attachments = kept
+	if err := setSecret(ctx, secstore, secretID, sec, version); err != nil {
+		return err
+	}
+	destroyChunks(ctx, secstore, attachment)
+	return nil
+}
+
+// this function will copy the chunks of the attachments of the secret from one store to another
+// chunks already in the target are kept
+func copyAttachmentChunks(ctx context.Context, source, target SecretStore, sec secret.Secret) error {
+	for _, a := range sec.Attachments {
+		for n := 0; n < a.Chunks; n++ {
+			if _, _, err := target.Backend.Get(ctx, attachmentChunkPath(target, a.ChunkID, n), 0); err == nil {
+				continue
+			}
+			entry, _, err := source.Backend.Get(ctx, attachmentChunkPath(source, a.ChunkID, n), 0)
+			if err != nil {
+				return fmt.Errorf("attachment %s chunk %d: %w", a.Name, n, err)
+			}
+			if _, err := target.Backend.Put(ctx, attachmentChunkPath(target, a.ChunkID, n), entry, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package securestore
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Backend is the storage used by a SecretStore
+// paths are relative to the store mount (for example <APPNAME>/<secretID>), every entry keeps a list of versions
+type Backend interface {
+	// Get return the data of the entry at the given version (0 for the latest) and the version read
+	// ErrNotFound is returned if the entry does not exist or the version is deleted
+	Get(ctx context.Context, path string, version int64) (map[string]interface{}, int64, error)
+	// Put write a new version of the entry only if its current version is cas (0 to create it)
+	// ErrVersionMismatch is returned otherwise
+	Put(ctx context.Context, path string, data map[string]interface{}, cas int64) (int64, error)
+	// Delete soft delete a version of the entry
+	Delete(ctx context.Context, path string, version int64) error
+	// Undelete restore a soft deleted version of the entry
+	Undelete(ctx context.Context, path string, version int64) error
+	// Destroy remove the entry and all its versions
+	Destroy(ctx context.Context, path string) error
+	// DestroyVersions permanently remove the data of the versions of the entry, the versions stay in its history
+	DestroyVersions(ctx context.Context, path string, versions []int64) error
+	// List return the keys under the folder, sub folders end with "/"
+	List(ctx context.Context, folder string) ([]string, error)
+	// Versions return the versions of the entry, oldest first
+	Versions(ctx context.Context, path string) ([]SecretVersion, error)
+	// Versioned is false when the backend keep no history (no versions, trash or check-and-set)
+	Versioned() bool
+
+	// WriteCubbyhole store data in the private cubbyhole
+	WriteCubbyhole(ctx context.Context, path string, data map[string]interface{}) error
+	// ReadCubbyhole read data from the private cubbyhole
+	ReadCubbyhole(ctx context.Context, path string) (map[string]interface{}, error)
+	// ListCubbyhole return the keys of the private cubbyhole
+	ListCubbyhole(ctx context.Context) ([]string, error)
+	// Wrap return a single use token giving access to the cubbyhole entry for ttl
+	Wrap(ctx context.Context, path string, ttl time.Duration) (string, error)
+	// Unwrap return the data wrapped by token
+	Unwrap(ctx context.Context, token string) (map[string]interface{}, error)
+}
+
+// ErrNotFound is returned by a Backend when an entry or a version does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrVersionMismatch is returned by a Backend when a check-and-set write is rejected
+var ErrVersionMismatch = errors.New("check-and-set version mismatch")
+
+// ErrNotSupported is returned by a Backend for an operation it can not do (for example the history on a KV v1 mount)
+var ErrNotSupported = errors.New("not supported by this storage (KV version 1 mount?)")
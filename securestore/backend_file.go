@@ -0,0 +1,140 @@
+package securestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abruno06/myvault/crypto"
+)
+
+// FileBackend keep the entries in a local file encrypted with a key derived from a passphrase
+// the whole content is loaded in memory and the file is rewritten after every change
+type FileBackend struct {
+	MemoryBackend
+	path string
+	salt []byte
+	key  []byte
+}
+
+// the format of the file
+type fileContent struct {
+	Salt []byte `json:"salt"`
+	Data []byte `json:"data"`
+}
+
+// open the encrypted file, the file is created on the first write if it does not exist
+func NewFileBackend(path, passphrase string) (*FileBackend, error) {
+	b := &FileBackend{MemoryBackend: MemoryBackend{state: newMemoryState()}, path: path}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if b.salt, err = crypto.NewSalt(); err != nil {
+			return nil, err
+		}
+		b.key = crypto.DeriveKey(passphrase, b.salt)
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var content fileContent
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return nil, fmt.Errorf("%s is not a valid store file: %v", path, err)
+	}
+	b.salt = content.Salt
+	b.key = crypto.DeriveKey(passphrase, b.salt)
+	plain, err := crypto.Open(b.key, content.Data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt %s, wrong passphrase?", path)
+	}
+	if err := json.Unmarshal(plain, &b.state); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// encrypt and write the content to the file
+func (b *FileBackend) save() error {
+	b.mu.Lock()
+	plain, err := json.Marshal(b.state)
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	sealed, err := crypto.Seal(b.key, plain)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(fileContent{Salt: b.salt, Data: sealed})
+	if err != nil {
+		return err
+	}
+	//write to a temporary file first so a failure never leaves a truncated store
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path)
+}
+
+func (b *FileBackend) Put(ctx context.Context, path string, data map[string]interface{}, cas int64) (int64, error) {
+	version, err := b.MemoryBackend.Put(ctx, path, data, cas)
+	if err != nil {
+		return version, err
+	}
+	return version, b.save()
+}
+
+func (b *FileBackend) Delete(ctx context.Context, path string, version int64) error {
+	if err := b.MemoryBackend.Delete(ctx, path, version); err != nil {
+		return err
+	}
+	return b.save()
+}
+
+func (b *FileBackend) Undelete(ctx context.Context, path string, version int64) error {
+	if err := b.MemoryBackend.Undelete(ctx, path, version); err != nil {
+		return err
+	}
+	return b.save()
+}
+
+func (b *FileBackend) DestroyVersions(ctx context.Context, path string, versions []int64) error {
+	if err := b.MemoryBackend.DestroyVersions(ctx, path, versions); err != nil {
+		return err
+	}
+	return b.save()
+}
+
+func (b *FileBackend) Destroy(ctx context.Context, path string) error {
+	if err := b.MemoryBackend.Destroy(ctx, path); err != nil {
+		return err
+	}
+	return b.save()
+}
+
+func (b *FileBackend) WriteCubbyhole(ctx context.Context, path string, data map[string]interface{}) error {
+	if err := b.MemoryBackend.WriteCubbyhole(ctx, path, data); err != nil {
+		return err
+	}
+	return b.save()
+}
+
+func (b *FileBackend) Wrap(ctx context.Context, path string, ttl time.Duration) (string, error) {
+	token, err := b.MemoryBackend.Wrap(ctx, path, ttl)
+	if err != nil {
+		return "", err
+	}
+	return token, b.save()
+}
+
+func (b *FileBackend) Unwrap(ctx context.Context, token string) (map[string]interface{}, error) {
+	data, err := b.MemoryBackend.Unwrap(ctx, token)
+	if saveErr := b.save(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return data, err
+}
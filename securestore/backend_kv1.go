@@ -0,0 +1,93 @@
+package securestore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// VaultKV1Backend store the entries in a Vault KV version 1 mount
+// a KV v1 entry has no version, the history, the trash and the check-and-set are not available
+type VaultKV1Backend struct {
+	VaultBackend
+}
+
+func (b *VaultKV1Backend) Versioned() bool {
+	return false
+}
+
+func (b *VaultKV1Backend) Get(ctx context.Context, path string, version int64) (map[string]interface{}, int64, error) {
+	if version > 0 {
+		return nil, 0, fmt.Errorf("reading version %d: %w", version, ErrNotSupported)
+	}
+	resp, err := b.Client.Secrets.KvV1Read(ctx, path, vault.WithMountPath(b.Mountpath))
+	if err != nil {
+		return nil, 0, vaultError(err)
+	}
+	if resp.Data == nil {
+		return nil, 0, ErrNotFound
+	}
+	return resp.Data, 0, nil
+}
+
+// the cas version is ignored, the entry is always overwritten
+func (b *VaultKV1Backend) Put(ctx context.Context, path string, data map[string]interface{}, cas int64) (int64, error) {
+	_, err := b.Client.Secrets.KvV1Write(ctx, path, data, vault.WithMountPath(b.Mountpath))
+	return 0, err
+}
+
+// the entry is permanently removed whatever the version
+func (b *VaultKV1Backend) Delete(ctx context.Context, path string, version int64) error {
+	_, err := b.Client.Secrets.KvV1Delete(ctx, path, vault.WithMountPath(b.Mountpath))
+	return vaultError(err)
+}
+
+func (b *VaultKV1Backend) Undelete(ctx context.Context, path string, version int64) error {
+	return fmt.Errorf("undelete: %w", ErrNotSupported)
+}
+
+func (b *VaultKV1Backend) DestroyVersions(ctx context.Context, path string, versions []int64) error {
+	return fmt.Errorf("destroy versions: %w", ErrNotSupported)
+}
+
+func (b *VaultKV1Backend) Destroy(ctx context.Context, path string) error {
+	_, err := b.Client.Secrets.KvV1Delete(ctx, path, vault.WithMountPath(b.Mountpath))
+	return vaultError(err)
+}
+
+func (b *VaultKV1Backend) List(ctx context.Context, folder string) ([]string, error) {
+	resp, err := b.Client.Secrets.KvV1List(ctx, folder, vault.WithMountPath(b.Mountpath))
+	if err != nil {
+		return nil, vaultError(err)
+	}
+	return resp.Data.Keys, nil
+}
+
+func (b *VaultKV1Backend) Versions(ctx context.Context, path string) ([]SecretVersion, error) {
+	return nil, fmt.Errorf("version history: %w", ErrNotSupported)
+}
+
+// return the KV version (1 or 2) of the mount using sys/mounts
+// the ui mounts endpoint, readable by any token allowed on the mount, is used if sys/mounts is denied
+func detectKVVersion(ctx context.Context, client *vault.Client, mountpath string) (int, error) {
+	var mountType string
+	var options map[string]interface{}
+	resp, err := client.System.MountsReadConfiguration(ctx, mountpath)
+	if err == nil {
+		mountType, options = resp.Data.Type, resp.Data.Options
+	} else {
+		uiResp, uiErr := client.Read(ctx, "sys/internal/ui/mounts/"+mountpath)
+		if uiErr != nil {
+			return 0, err
+		}
+		mountType, _ = uiResp.Data["type"].(string)
+		options, _ = uiResp.Data["options"].(map[string]interface{})
+	}
+	version, _ := options["version"].(string)
+	// a kv mount created without the version option is a version 1 mount
+	if version == "1" || (version == "" && (mountType == "kv" || mountType == "generic")) {
+		return 1, nil
+	}
+	return 2, nil
+}
@@ -0,0 +1,250 @@
+package securestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryBackend keep the entries in memory, it is used for tests and as the base of the FileBackend
+type MemoryBackend struct {
+	mu    sync.Mutex
+	state memoryState
+}
+
+// the content of a MemoryBackend, exported fields allow the FileBackend to save it
+type memoryState struct {
+	Entries   map[string][]memoryVersion        `json:"entries"`
+	Cubbyhole map[string]map[string]interface{} `json:"cubbyhole"`
+	Wrapped   map[string]memoryWrap             `json:"wrapped"`
+}
+
+// one version of an entry, version n is stored at index n-1
+type memoryVersion struct {
+	Data      map[string]interface{} `json:"data"`
+	Created   time.Time              `json:"created"`
+	Deleted   time.Time              `json:"deleted,omitempty"`
+	Destroyed bool                   `json:"destroyed,omitempty"`
+}
+
+// a wrapped cubbyhole entry
+type memoryWrap struct {
+	Data   map[string]interface{} `json:"data"`
+	Expire time.Time              `json:"expire"`
+}
+
+// create an empty MemoryBackend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{state: newMemoryState()}
+}
+
+func newMemoryState() memoryState {
+	return memoryState{
+		Entries:   make(map[string][]memoryVersion),
+		Cubbyhole: make(map[string]map[string]interface{}),
+		Wrapped:   make(map[string]memoryWrap),
+	}
+}
+
+// return a deep copy of the data so callers can not modify the stored value
+func copyData(data map[string]interface{}) map[string]interface{} {
+	raw, _ := json.Marshal(data)
+	rValue := make(map[string]interface{})
+	json.Unmarshal(raw, &rValue)
+	return rValue
+}
+
+func (b *MemoryBackend) Versioned() bool {
+	return true
+}
+
+func (b *MemoryBackend) Get(ctx context.Context, path string, version int64) (map[string]interface{}, int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	versions := b.state.Entries[path]
+	if version == 0 {
+		version = int64(len(versions))
+	}
+	if version < 1 || version > int64(len(versions)) {
+		return nil, 0, ErrNotFound
+	}
+	v := versions[version-1]
+	if !v.Deleted.IsZero() || v.Destroyed {
+		return nil, version, ErrNotFound
+	}
+	return copyData(v.Data), version, nil
+}
+
+func (b *MemoryBackend) Put(ctx context.Context, path string, data map[string]interface{}, cas int64) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	versions := b.state.Entries[path]
+	if cas != int64(len(versions)) {
+		return 0, ErrVersionMismatch
+	}
+	b.state.Entries[path] = append(versions, memoryVersion{Data: copyData(data), Created: time.Now().UTC()})
+	return int64(len(versions) + 1), nil
+}
+
+// return the version of the entry or an error if it does not exist
+func (b *MemoryBackend) version(path string, version int64) (*memoryVersion, error) {
+	versions := b.state.Entries[path]
+	if version < 1 || version > int64(len(versions)) {
+		return nil, ErrNotFound
+	}
+	return &versions[version-1], nil
+}
+
+func (b *MemoryBackend) Delete(ctx context.Context, path string, version int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, err := b.version(path, version)
+	if err != nil {
+		return err
+	}
+	if v.Deleted.IsZero() {
+		v.Deleted = time.Now().UTC()
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Undelete(ctx context.Context, path string, version int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, err := b.version(path, version)
+	if err != nil {
+		return err
+	}
+	v.Deleted = time.Time{}
+	return nil
+}
+
+func (b *MemoryBackend) DestroyVersions(ctx context.Context, path string, versions []int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, version := range versions {
+		v, err := b.version(path, version)
+		if err != nil {
+			return err
+		}
+		v.Data, v.Destroyed = nil, true
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Destroy(ctx context.Context, path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.state.Entries[path]; !ok {
+		return ErrNotFound
+	}
+	delete(b.state.Entries, path)
+	return nil
+}
+
+func (b *MemoryBackend) List(ctx context.Context, folder string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if folder != "" && !strings.HasSuffix(folder, "/") {
+		folder += "/"
+	}
+	seen := make(map[string]bool)
+	var rValue []string
+	for path := range b.state.Entries {
+		if !strings.HasPrefix(path, folder) {
+			continue
+		}
+		key := strings.TrimPrefix(path, folder)
+		if i := strings.Index(key, "/"); i >= 0 {
+			key = key[:i+1]
+		}
+		if !seen[key] {
+			seen[key] = true
+			rValue = append(rValue, key)
+		}
+	}
+	if len(rValue) == 0 {
+		return nil, ErrNotFound
+	}
+	sort.Strings(rValue)
+	return rValue, nil
+}
+
+func (b *MemoryBackend) Versions(ctx context.Context, path string) ([]SecretVersion, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	versions, ok := b.state.Entries[path]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var rValue []SecretVersion
+	for i, v := range versions {
+		sv := SecretVersion{Version: int64(i + 1), CreatedTime: v.Created, Destroyed: v.Destroyed}
+		if !v.Deleted.IsZero() {
+			sv.DeletionTime = v.Deleted.Format(time.RFC3339Nano)
+		}
+		rValue = append(rValue, sv)
+	}
+	return rValue, nil
+}
+
+func (b *MemoryBackend) WriteCubbyhole(ctx context.Context, path string, data map[string]interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state.Cubbyhole[path] = copyData(data)
+	return nil
+}
+
+func (b *MemoryBackend) ReadCubbyhole(ctx context.Context, path string) (map[string]interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.state.Cubbyhole[path]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyData(data), nil
+}
+
+func (b *MemoryBackend) ListCubbyhole(ctx context.Context) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var rValue []string
+	for k := range b.state.Cubbyhole {
+		rValue = append(rValue, k)
+	}
+	sort.Strings(rValue)
+	return rValue, nil
+}
+
+func (b *MemoryBackend) Wrap(ctx context.Context, path string, ttl time.Duration) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.state.Cubbyhole[path]
+	if !ok {
+		return "", ErrNotFound
+	}
+	token := uuid.New().String()
+	b.state.Wrapped[token] = memoryWrap{Data: copyData(data), Expire: time.Now().Add(ttl)}
+	return token, nil
+}
+
+func (b *MemoryBackend) Unwrap(ctx context.Context, token string) (map[string]interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, ok := b.state.Wrapped[token]
+	if !ok {
+		return nil, fmt.Errorf("wrapping token is not valid or does not exist")
+	}
+	//a wrapping token can only be used once
+	delete(b.state.Wrapped, token)
+	if time.Now().After(w.Expire) {
+		return nil, fmt.Errorf("wrapping token has expired")
+	}
+	return w.Data, nil
+}
@@ -0,0 +1,189 @@
+package securestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+)
+
+// VaultBackend store the entries in a Vault KV v2 mount
+type VaultBackend struct {
+	Client    *vault.Client
+	Mountpath string
+}
+
+// convert a vault not found error to ErrNotFound
+func vaultError(err error) error {
+	if vault.IsErrorStatus(err, http.StatusNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// check if vault rejected a write because the check-and-set version did not match
+func isCASError(err error) bool {
+	if err == nil || !vault.IsErrorStatus(err, http.StatusBadRequest) {
+		return false
+	}
+	var responseError *vault.ResponseError
+	if errors.As(err, &responseError) {
+		for _, e := range responseError.Errors {
+			if strings.Contains(e, "check-and-set") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extract the version from a kv v2 metadata map
+func metadataVersion(metadata map[string]interface{}) int64 {
+	switch v := metadata["version"].(type) {
+	case json.Number:
+		version, _ := v.Int64()
+		return version
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func (b *VaultBackend) Versioned() bool {
+	return true
+}
+
+func (b *VaultBackend) Get(ctx context.Context, path string, version int64) (map[string]interface{}, int64, error) {
+	options := []vault.RequestOption{vault.WithMountPath(b.Mountpath)}
+	if version > 0 {
+		options = append(options, vault.WithQueryParameters(url.Values{"version": {strconv.FormatInt(version, 10)}}))
+	}
+	s, err := b.Client.Secrets.KvV2Read(ctx, path, options...)
+	if err != nil {
+		return nil, 0, vaultError(err)
+	}
+	if s.Data.Data == nil {
+		return nil, metadataVersion(s.Data.Metadata), ErrNotFound
+	}
+	return s.Data.Data, metadataVersion(s.Data.Metadata), nil
+}
+
+func (b *VaultBackend) Put(ctx context.Context, path string, data map[string]interface{}, cas int64) (int64, error) {
+	resp, err := b.Client.Secrets.KvV2Write(ctx, path, schema.KvV2WriteRequest{
+		Data:    data,
+		Options: map[string]interface{}{"cas": cas},
+	},
+		vault.WithMountPath(b.Mountpath))
+	if isCASError(err) {
+		return 0, ErrVersionMismatch
+	}
+	if err != nil {
+		return 0, err
+	}
+	return resp.Data.Version, nil
+}
+
+func (b *VaultBackend) Delete(ctx context.Context, path string, version int64) error {
+	_, err := b.Client.Secrets.KvV2DeleteVersions(ctx, path, schema.KvV2DeleteVersionsRequest{
+		Versions: []int32{int32(version)},
+	}, vault.WithMountPath(b.Mountpath))
+	return vaultError(err)
+}
+
+func (b *VaultBackend) Undelete(ctx context.Context, path string, version int64) error {
+	_, err := b.Client.Secrets.KvV2UndeleteVersions(ctx, path, schema.KvV2UndeleteVersionsRequest{
+		Versions: []int32{int32(version)},
+	}, vault.WithMountPath(b.Mountpath))
+	return vaultError(err)
+}
+
+func (b *VaultBackend) DestroyVersions(ctx context.Context, path string, versions []int64) error {
+	request := schema.KvV2DestroyVersionsRequest{}
+	for _, v := range versions {
+		request.Versions = append(request.Versions, int32(v))
+	}
+	_, err := b.Client.Secrets.KvV2DestroyVersions(ctx, path, request, vault.WithMountPath(b.Mountpath))
+	return vaultError(err)
+}
+
+func (b *VaultBackend) Destroy(ctx context.Context, path string) error {
+	_, err := b.Client.Secrets.KvV2DeleteMetadataAndAllVersions(ctx, path, vault.WithMountPath(b.Mountpath))
+	return vaultError(err)
+}
+
+func (b *VaultBackend) List(ctx context.Context, folder string) ([]string, error) {
+	resp, err := b.Client.Secrets.KvV2List(ctx, folder, vault.WithMountPath(b.Mountpath))
+	if err != nil {
+		return nil, vaultError(err)
+	}
+	return resp.Data.Keys, nil
+}
+
+func (b *VaultBackend) Versions(ctx context.Context, path string) ([]SecretVersion, error) {
+	resp, err := b.Client.Secrets.KvV2ReadMetadata(ctx, path, vault.WithMountPath(b.Mountpath))
+	if err != nil {
+		return nil, vaultError(err)
+	}
+	var rValue []SecretVersion
+	for k, v := range resp.Data.Versions {
+		version, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			continue
+		}
+		sv := SecretVersion{Version: version}
+		if detail, ok := v.(map[string]interface{}); ok {
+			if created, ok := detail["created_time"].(string); ok {
+				sv.CreatedTime, _ = time.Parse(time.RFC3339Nano, created)
+			}
+			sv.DeletionTime, _ = detail["deletion_time"].(string)
+			sv.Destroyed, _ = detail["destroyed"].(bool)
+		}
+		rValue = append(rValue, sv)
+	}
+	sortVersions(rValue)
+	return rValue, nil
+}
+
+func (b *VaultBackend) WriteCubbyhole(ctx context.Context, path string, data map[string]interface{}) error {
+	_, err := b.Client.Secrets.CubbyholeWrite(ctx, path, data, vault.WithMountPath(b.Mountpath))
+	return err
+}
+
+func (b *VaultBackend) ReadCubbyhole(ctx context.Context, path string) (map[string]interface{}, error) {
+	resp, err := b.Client.Secrets.CubbyholeRead(ctx, path, vault.WithMountPath(b.Mountpath))
+	if err != nil {
+		return nil, vaultError(err)
+	}
+	return resp.Data, nil
+}
+
+func (b *VaultBackend) ListCubbyhole(ctx context.Context) ([]string, error) {
+	resp, err := b.Client.Secrets.CubbyholeList(ctx, "", vault.WithMountPath(b.Mountpath))
+	if err != nil {
+		return nil, vaultError(err)
+	}
+	return resp.Data.Keys, nil
+}
+
+func (b *VaultBackend) Wrap(ctx context.Context, path string, ttl time.Duration) (string, error) {
+	resp, err := b.Client.Secrets.CubbyholeRead(ctx, path, vault.WithMountPath(b.Mountpath), vault.WithResponseWrapping(ttl))
+	if err != nil {
+		return "", err
+	}
+	return resp.WrapInfo.Token, nil
+}
+
+func (b *VaultBackend) Unwrap(ctx context.Context, token string) (map[string]interface{}, error) {
+	resp, err := vault.Unwrap[map[string]interface{}](ctx, b.Client, token)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
@@ -0,0 +1,55 @@
+package securestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/abruno06/myvault/crypto"
+)
+
+// BreachEntry is a secret whose credential is in the breach list
+type BreachEntry struct {
+	SecretID string
+	Count    int
+}
+
+// this function will check the credential of every secret against the breach list
+func AuditBreached(ctx context.Context, secstore SecretStore, list *crypto.BreachList) ([]BreachEntry, error) {
+	secrets, err := getFolderSecrets(ctx, secstore, "")
+	if err != nil {
+		return nil, err
+	}
+	var rValue []BreachEntry
+	for _, id := range sortedKeys(secrets) {
+		credential := secrets[id].Credential
+		if credential == "" {
+			continue
+		}
+		count, err := list.Count(credential)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			rValue = append(rValue, BreachEntry{SecretID: id, Count: count})
+		}
+	}
+	return rValue, nil
+}
+
+// this function display the breached secrets in tabular format
+func DisplayBreachAudit(entries []BreachEntry) {
+	if len(entries) == 0 {
+		fmt.Println("No breached credential found")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	format := "%s\t%s\n"
+	fmt.Fprintf(w, format, "ID", "Seen in breaches")
+	for _, e := range entries {
+		fmt.Fprintf(w, format, e.SecretID, strconv.Itoa(e.Count))
+	}
+	w.Flush()
+}
@@ -0,0 +1,247 @@
+package securestore
+
+import (
+	"context"
+	gocrypto "crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/abruno06/myvault/crypto"
+	"github.com/abruno06/myvault/secret"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// The offline cache is a local copy of the secrets of the appname, encrypted with a key
+// derived from a passphrase or wrapped to the public key of the Yubikey key management slot
+// when vault can not be reached the cache give a read only access to the secrets
+
+// CacheLock hold what is needed to lock (save) or unlock (open) the offline cache
+type CacheLock struct {
+	// Passphrase is used when the cache is locked with a passphrase
+	Passphrase string
+	// PublicKey is used to save and PrivateKey to open a cache locked with the key management slot
+	PublicKey  gocrypto.PublicKey
+	PrivateKey gocrypto.PrivateKey
+}
+
+// the format of the cache file
+type cacheContent struct {
+	Appname    string             `json:"appname"`
+	Fetched    time.Time          `json:"fetched"`
+	Salt       []byte             `json:"salt,omitempty"`
+	WrappedKey *crypto.WrappedKey `json:"wrappedkey,omitempty"`
+	Data       []byte             `json:"data"`
+	// Chunks are the chunks of the attachments ("<ChunkID>/<n>"), encrypted with the same key
+	Chunks []byte `json:"chunks,omitempty"`
+}
+
+// ErrReadOnly is returned when trying to modify the offline cache
+var ErrReadOnly = errors.New("offline cache is read only")
+
+// ReadOnlyBackend give a read only access to a backend, it is used for the offline cache
+type ReadOnlyBackend struct {
+	Backend
+	// Fetched is the time the data was read from vault
+	Fetched time.Time
+}
+
+func (b *ReadOnlyBackend) Versioned() bool {
+	return false
+}
+
+func (b *ReadOnlyBackend) Put(ctx context.Context, path string, data map[string]interface{}, cas int64) (int64, error) {
+	return 0, ErrReadOnly
+}
+
+func (b *ReadOnlyBackend) Delete(ctx context.Context, path string, version int64) error {
+	return ErrReadOnly
+}
+
+func (b *ReadOnlyBackend) Undelete(ctx context.Context, path string, version int64) error {
+	return ErrReadOnly
+}
+
+func (b *ReadOnlyBackend) Destroy(ctx context.Context, path string) error {
+	return ErrReadOnly
+}
+
+func (b *ReadOnlyBackend) DestroyVersions(ctx context.Context, path string, versions []int64) error {
+	return ErrReadOnly
+}
+
+func (b *ReadOnlyBackend) WriteCubbyhole(ctx context.Context, path string, data map[string]interface{}) error {
+	return ErrReadOnly
+}
+
+func (b *ReadOnlyBackend) Wrap(ctx context.Context, path string, ttl time.Duration) (string, error) {
+	return "", ErrReadOnly
+}
+
+// return the age banner of an offline store, empty if the store is online
+func OfflineBanner(secstore SecretStore) string {
+	ro, ok := secstore.Backend.(*ReadOnlyBackend)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("OFFLINE read only copy fetched at %s (%s ago)", ro.Fetched.Local().Format("2006-01-02 15:04:05"), time.Since(ro.Fetched).Round(time.Minute))
+}
+
+// check if the error means vault could not be reached (as opposed to a refused login or a bad pin)
+func IsUnreachable(err error) bool {
+	var responseError *vault.ResponseError
+	if err == nil || errors.As(err, &responseError) {
+		return false
+	}
+	var urlError *url.Error
+	var netError net.Error
+	return errors.As(err, &urlError) || errors.As(err, &netError)
+}
+
+// this function will save all the secrets of the appname into the encrypted cache file
+func SaveCache(ctx context.Context, secstore SecretStore, path string, lock CacheLock) error {
+	secrets, err := getAllSecrets(ctx, secstore)
+	if err != nil {
+		return err
+	}
+	data := make(map[string]interface{})
+	chunks := make(map[string]interface{})
+	for k, v := range secrets {
+		data[k] = secret.ConvertFromSecret(v)
+		//the large attachments are stored apart from the secret, they are needed to extract them offline
+		for _, a := range v.Attachments {
+			for n := 0; n < a.Chunks; n++ {
+				chunk, _, err := secstore.Backend.Get(ctx, attachmentChunkPath(secstore, a.ChunkID, n), 0)
+				if err != nil {
+					log.Printf("Secret ID: %s attachment %s not cached: %v\n", k, a.Name, err)
+					break
+				}
+				chunks[a.ChunkID+"/"+strconv.Itoa(n)] = chunk
+			}
+		}
+	}
+	plain, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	plainChunks, err := json.Marshal(chunks)
+	if err != nil {
+		return err
+	}
+	content := cacheContent{Appname: secstore.Appname, Fetched: time.Now().UTC()}
+	var key []byte
+	switch {
+	case lock.Passphrase != "":
+		if content.Salt, err = crypto.NewSalt(); err != nil {
+			return err
+		}
+		key = crypto.DeriveKey(lock.Passphrase, content.Salt)
+	case lock.PublicKey != nil:
+		if key, err = crypto.NewDataKey(); err != nil {
+			return err
+		}
+		wrapped, err := crypto.WrapKey(lock.PublicKey, key)
+		if err != nil {
+			return err
+		}
+		content.WrappedKey = &wrapped
+	default:
+		return errors.New("no passphrase or public key to lock the cache")
+	}
+	if content.Data, err = crypto.Seal(key, plain); err != nil {
+		return err
+	}
+	if content.Chunks, err = crypto.Seal(key, plainChunks); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// read the cache file without decrypting it
+func readCacheContent(path string) (cacheContent, error) {
+	var content cacheContent
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return content, err
+	}
+	err = json.Unmarshal(raw, &content)
+	return content, err
+}
+
+// return how the cache file is locked: "passphrase" or "yubikey"
+func CacheLockMode(path string) (string, error) {
+	content, err := readCacheContent(path)
+	if err != nil {
+		return "", err
+	}
+	if content.WrappedKey != nil {
+		return "yubikey", nil
+	}
+	return "passphrase", nil
+}
+
+// this function will open the encrypted cache file and return a read only SecretStore
+func OpenCache(path string, lock CacheLock) (SecretStore, error) {
+	content, err := readCacheContent(path)
+	if err != nil {
+		return SecretStore{}, err
+	}
+	var key []byte
+	if content.WrappedKey != nil {
+		if lock.PrivateKey == nil {
+			return SecretStore{}, errors.New("the cache is locked with the Yubikey key management slot")
+		}
+		if key, err = crypto.UnwrapKey(lock.PrivateKey, *content.WrappedKey); err != nil {
+			return SecretStore{}, fmt.Errorf("unable to unlock the cache: %v", err)
+		}
+	} else {
+		key = crypto.DeriveKey(lock.Passphrase, content.Salt)
+	}
+	plain, err := crypto.Open(key, content.Data)
+	if err != nil {
+		return SecretStore{}, errors.New("unable to decrypt the cache, wrong passphrase?")
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return SecretStore{}, err
+	}
+	ctx := context.Background()
+	mem := NewMemoryBackend()
+	secstore := SecretStore{Appname: content.Appname, Backend: mem}
+	for k, v := range data {
+		if item, ok := v.(map[string]interface{}); ok {
+			mem.Put(ctx, secretPath(secstore, k), item, 0)
+		}
+	}
+	//a cache saved before the attachments were cached has no chunks
+	if content.Chunks != nil {
+		plainChunks, err := crypto.Open(key, content.Chunks)
+		if err != nil {
+			return SecretStore{}, errors.New("unable to decrypt the cache attachments")
+		}
+		var chunks map[string]map[string]interface{}
+		if err := json.Unmarshal(plainChunks, &chunks); err != nil {
+			return SecretStore{}, err
+		}
+		for k, v := range chunks {
+			mem.Put(ctx, secstore.Appname+".attachments/"+k, v, 0)
+		}
+	}
+	secstore.Backend = &ReadOnlyBackend{Backend: mem, Fetched: content.Fetched}
+	return secstore, nil
+}
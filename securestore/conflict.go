@@ -0,0 +1,36 @@
+package securestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ConflictError is returned when a write is rejected because the secret changed since it was read
+type ConflictError struct {
+	SecretID string
+	// Version is the version the write was based on
+	Version int64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("Secret ID: %s was modified by someone else since version %d", e.SecretID, e.Version)
+}
+
+// check if the error is a ConflictError
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// return the current version of an entry (0 if the entry never existed)
+func currentVersion(ctx context.Context, secstore SecretStore, path string) (int64, error) {
+	versions, err := secstore.Backend.Versions(ctx, path)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrNotSupported) {
+		return 0, nil
+	}
+	if err != nil || len(versions) == 0 {
+		return 0, err
+	}
+	return versions[len(versions)-1].Version, nil
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/abruno06/myvault/config"
@@ -18,13 +19,26 @@ import (
 	"github.com/hashicorp/vault-client-go/schema"
 )
 
-// connect to vault with specific tls config
-func ConnectVaultWithTLSConfig(ctx context.Context, tlsConfig *tls.Config) (SecretStore, error) {
-	// prepare a client with the given base address
+// return a new vault client for the VAULTURL, every request (login included) is sent to the namespace
+// an empty namespace is the root namespace
+func newVaultClient(namespace string) (*vault.Client, error) {
 	client, err := vault.New(
 		vault.WithAddress(config.ReadVaultURL()),
 		vault.WithRequestTimeout(30*time.Second),
 	)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.SetNamespace(namespace); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// connect to vault with specific tls config
+func ConnectVaultWithTLSConfig(ctx context.Context, tlsConfig *tls.Config) (SecretStore, error) {
+	// prepare a client with the given base address
+	client, err := newVaultClient(config.ReadNamespace())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -36,16 +50,13 @@ func ConnectVaultWithTLSConfig(ctx context.Context, tlsConfig *tls.Config) (Secr
 	resp, err := client.Auth.CertLogin(ctx, schema.CertLoginRequest{Name: config.ReadCertificateName()})
 	if err != nil {
 		fmt.Printf("CertLogin error: %v\n", err)
-		log.Fatal(err)
-	}
-	if err != nil {
-		log.Fatal(err)
+		return SecretStore{}, err
 	}
 	if err := client.SetToken(resp.Auth.ClientToken); err != nil {
 		log.Fatal(err)
 	}
 	fmt.Printf("Client Token: %v\n", resp.Auth.ClientToken)
-	return SecretStore{Client: client, Mountpath: config.ReadMountPath(), Appname: config.ReadAPPNAME()}, err
+	return vaultStore(ctx, client, config.ReadNamespace()), err
 }
 
 // connect to vault with yubikey
@@ -100,50 +111,105 @@ func ConnectVaulwithYubikey(ctx context.Context, yubikey *piv.YubiKey, pin strin
 func ConnectVaultWithUsernamePassword(ctx context.Context, username, password string) (SecretStore, error) {
 	// Prepare Vault Connection
 	// prepare a client with the given base address
-	client, err := vault.New(
-		vault.WithAddress(config.ReadVaultURL()),
-		vault.WithRequestTimeout(30*time.Second),
-	)
+	client, err := newVaultClient(config.ReadNamespace())
 	if err != nil {
 		log.Fatal(err)
 	}
 	// Authenticate with the Vault server using username and password
 	resp, err := client.Auth.UserpassLogin(ctx, username, schema.UserpassLoginRequest{Password: password})
 	if err != nil {
-		log.Fatal(err)
+		return SecretStore{}, err
 	}
 	if err := client.SetToken(resp.Auth.ClientToken); err != nil {
 		log.Fatal(err)
 
 	}
-	return SecretStore{Client: client, Mountpath: config.ReadMountPath(), Appname: config.ReadAPPNAME()}, err
+	return vaultStore(ctx, client, config.ReadNamespace()), err
 }
 
 // connect to vault using token
 func ConnectVaultWithToken(ctx context.Context, token string) (SecretStore, error) {
+	return ConnectVaultWithTokenNamespace(ctx, token, config.ReadNamespace())
+}
+
+// connect to vault using a token that belong to the given namespace
+func ConnectVaultWithTokenNamespace(ctx context.Context, token, namespace string) (SecretStore, error) {
 	// prepare a client with the given base address
-	client, err := vault.New(
-		vault.WithAddress(config.ReadVaultURL()),
-		vault.WithRequestTimeout(30*time.Second),
-	)
+	client, err := newVaultClient(namespace)
 	if err != nil {
 		log.Fatal(err)
 	}
 	if err := client.SetToken(token); err != nil {
 		log.Fatal(err)
 	}
-	return SecretStore{Client: client, Mountpath: config.ReadMountPath(), Appname: config.ReadAPPNAME()}, err
+	return vaultStore(ctx, client, namespace), err
 }
 
 // connect to vault in annonymous mode
 func ConnectVault(ctx context.Context) (SecretStore, error) {
 	// Prepare Vault Connection
-	client, err := vault.New(
-		vault.WithAddress(config.ReadVaultURL()),
-		vault.WithRequestTimeout(30*time.Second),
-	)
+	client, err := newVaultClient(config.ReadNamespace())
 	if err != nil {
 		log.Fatal(err)
 	}
-	return SecretStore{Client: client, Mountpath: config.ReadMountPath(), Appname: config.ReadAPPNAME()}, err
+	// an anonymous client can only unwrap, the mount version is not needed
+	mountpath := config.ReadMountPath()
+	return SecretStore{Client: client, Namespace: config.ReadNamespace(), Mountpath: mountpath, Appname: config.ReadAPPNAME(), Backend: &VaultBackend{Client: client, Mountpath: mountpath}}, err
+}
+
+// return a SecretStore using the vault KV mount as backend
+// the KV version of the mount is detected, a KV v1 mount disable the history, the trash and the conflict detection
+// with TRANSITKEY the backend encrypt the sensitive fields with the transit engine
+func vaultStore(ctx context.Context, client *vault.Client, namespace string) SecretStore {
+	mountpath := config.ReadMountPath()
+	secstore := SecretStore{Client: client, Namespace: namespace, Mountpath: mountpath, Appname: config.ReadAPPNAME(), Backend: &VaultBackend{Client: client, Mountpath: mountpath}}
+	version, err := detectKVVersion(ctx, client, mountpath)
+	if err != nil {
+		log.Printf("Unable to read the %s mount version, assuming KV version 2: %v\n", mountpath, err)
+	} else if version == 1 {
+		log.Printf("%s is a KV version 1 mount: history, trash and conflict detection are disabled\n", mountpath)
+		secstore.Backend = &VaultKV1Backend{VaultBackend{Client: client, Mountpath: mountpath}}
+	}
+	//the sensitive fields are encrypted with the transit key before they reach KV
+	if key := config.ReadTransitKey(); key != "" {
+		secstore.Backend = &TransitBackend{Backend: secstore.Backend, Client: client, Mountpath: config.ReadTransitMount(), Key: key}
+	}
+	return secstore
+}
+
+// open the local store selected by the BACKEND configuration
+// "file" use the encrypted STOREFILE unlocked with the passphrase, "memory" keep the secrets until the program exit
+func ConnectLocalStore(passphrase string) (SecretStore, error) {
+	var backend Backend
+	switch config.ReadBackend() {
+	case "file":
+		fileBackend, err := NewFileBackend(config.ReadStoreFile(), passphrase)
+		if err != nil {
+			return SecretStore{}, err
+		}
+		backend = fileBackend
+	case "memory":
+		backend = NewMemoryBackend()
+	default:
+		return SecretStore{}, fmt.Errorf("backend %s is not a local backend", config.ReadBackend())
+	}
+	return SecretStore{Mountpath: config.ReadMountPath(), Appname: config.ReadAPPNAME(), Backend: backend}, nil
+}
+
+// return a SecretStore using the same token in another namespace, the token must be allowed in that namespace
+// (a token of a parent namespace can be used in its child namespaces)
+// a store unlocked with a zero knowledge data key is not switched, the keyring is in the namespace of the login
+func SwitchNamespace(ctx context.Context, secstore SecretStore, namespace string) (SecretStore, error) {
+	if secstore.Client == nil {
+		return secstore, fmt.Errorf("namespaces are only available with the vault backend")
+	}
+	if _, ok := secstore.Backend.(*SealedBackend); ok {
+		return secstore, fmt.Errorf("namespaces can not be switched with zero knowledge encryption, use NAMESPACE or a profile")
+	}
+	namespace = strings.Trim(namespace, "/")
+	client := secstore.Client.Clone()
+	if err := client.SetNamespace(namespace); err != nil {
+		return secstore, err
+	}
+	return vaultStore(ctx, client, namespace), nil
 }
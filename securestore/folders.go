@@ -0,0 +1,289 @@
+package securestore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/abruno06/myvault/secret"
+)
+
+// Secret IDs can contain "/" to organise the secrets in folders, "team/db/admin" is the secret
+// admin of the folder team/db. A folder is written with a trailing "/" ("team/db/")
+
+// this function will check a secretID and return it without leading or trailing "/"
+func CleanSecretID(secretID string) (string, error) {
+	id := strings.Trim(strings.TrimSpace(secretID), "/")
+	if id == "" {
+		return "", fmt.Errorf("Secret ID: %q is empty", secretID)
+	}
+	for _, part := range strings.Split(id, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("Secret ID: %q is not valid", secretID)
+		}
+	}
+	return id, nil
+}
+
+// return the folder with a single trailing "/", the root folder is ""
+func cleanFolder(folder string) string {
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	if folder == "" {
+		return ""
+	}
+	return folder + "/"
+}
+
+// check if the id is a folder (ending with "/")
+func IsFolder(id string) bool {
+	return strings.HasSuffix(id, "/")
+}
+
+// this function will return all the secrets of a folder and its sub folders, the secrets that can not be read
+// are logged and skipped
+func getFolderSecrets(ctx context.Context, secstore SecretStore, folder string) (map[string]secret.Secret, error) {
+	rValue, _, err := readFolderSecrets(ctx, secstore, folder)
+	return rValue, err
+}
+
+// same as getFolderSecrets but also return the IDs of the secrets skipped, sorted
+func readFolderSecrets(ctx context.Context, secstore SecretStore, folder string) (map[string]secret.Secret, []string, error) {
+	keys, err := listSecretIDs(ctx, secstore, cleanFolder(folder))
+	if err != nil {
+		return nil, nil, err
+	}
+	rValue := make(map[string]secret.Secret)
+	var skipped []string
+	for _, k := range keys {
+		s, found, err := readSecret(ctx, secstore, k)
+		if err != nil {
+			log.Printf("Secret ID: %s skipped: %v\n", k, err)
+			skipped = append(skipped, k)
+			continue
+		}
+		if found {
+			rValue[k] = s
+		}
+	}
+	sort.Strings(skipped)
+	return rValue, skipped, nil
+}
+
+// this function will replace the folders of the list by the secretID they contain
+func ExpandSecretIDs(ctx context.Context, secstore SecretStore, secList []string) ([]string, error) {
+	var rValue []string
+	for _, id := range secList {
+		if !IsFolder(id) {
+			rValue = append(rValue, strings.TrimSpace(id))
+			continue
+		}
+		secrets, err := getFolderSecrets(ctx, secstore, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(secrets) == 0 {
+			fmt.Printf("Folder: %s is empty\n", id)
+		}
+		for k := range secrets {
+			rValue = append(rValue, k)
+		}
+	}
+	sort.Strings(rValue)
+	return rValue, nil
+}
+
+// this function list the secrets of a folder and its sub folders in tabular format
+func ListFolder(ctx context.Context, secstore SecretStore, folder string) error {
+	secrets, err := getFolderSecrets(ctx, secstore, folder)
+	if err != nil {
+		return err
+	}
+	displaySecrets(secstore, secrets)
+	return nil
+}
+
+// display the secrets in tabular format
+func displaySecrets(secstore SecretStore, secrets map[string]secret.Secret) {
+	if banner := OfflineBanner(secstore); banner != "" {
+		fmt.Println(banner)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	columns := secret.SecretFieldNames
+	format := "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
+	fmt.Fprintf(w, format, "ID", "Type", columns[0], columns[1], columns[2], columns[3], columns[4], columns[5], columns[6])
+	for _, k := range sortedKeys(secrets) {
+		s := secrets[k]
+		//a typed secret show its first field in Username and its main secret in Credential
+		fmt.Fprintf(w, format, k, s.TypeName(), s.Summary(), firstLine(s.MainSecret()), s.URL, s.LastUpdate.UTC().Format("2006-01-02 15:04:05"), s.LastUpdateBy, s.Comment, strings.Join(s.Tags, ","))
+	}
+	w.Flush()
+}
+
+// return the first line of a multiline value followed by "..."
+func firstLine(value string) string {
+	line, _, multiline := strings.Cut(strings.TrimRight(value, "\n"), "\n")
+	if multiline {
+		return line + "..."
+	}
+	return line
+}
+
+// return the keys of the map sorted case-insensitively
+func sortedKeys(secrets map[string]secret.Secret) []string {
+	var keys []string
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
+	})
+	return keys
+}
+
+// a node of the folder tree
+type treeNode struct {
+	children map[string]*treeNode
+	secret   bool
+}
+
+// build the tree of the secretIDs relative to the folder
+func buildTree(ids []string, folder string) *treeNode {
+	root := &treeNode{children: map[string]*treeNode{}}
+	for _, id := range ids {
+		node := root
+		parts := strings.Split(strings.TrimPrefix(id, folder), "/")
+		for i, part := range parts {
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{children: map[string]*treeNode{}}
+				node.children[part] = child
+			}
+			if i == len(parts)-1 {
+				child.secret = true
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+// return the lines of the tree view, folders are displayed with a trailing "/"
+func treeLines(node *treeNode, prefix string) []string {
+	var names []string
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	var rValue []string
+	for i, name := range names {
+		child := node.children[name]
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+		label := name
+		if len(child.children) > 0 {
+			label += "/"
+			// a secret can have the same name as a folder
+			if child.secret {
+				rValue = append(rValue, prefix+branch+name)
+			}
+		}
+		rValue = append(rValue, prefix+branch+label)
+		rValue = append(rValue, treeLines(child, prefix+indent)...)
+	}
+	return rValue
+}
+
+// this function will display the secretIDs of a folder as a tree
+func ListSecretsTree(ctx context.Context, secstore SecretStore, folder string) error {
+	secrets, err := getFolderSecrets(ctx, secstore, folder)
+	if err != nil {
+		return err
+	}
+	if banner := OfflineBanner(secstore); banner != "" {
+		fmt.Println(banner)
+	}
+	folder = cleanFolder(folder)
+	if folder == "" {
+		fmt.Println(secstore.Appname + "/")
+	} else {
+		fmt.Println(folder)
+	}
+	for _, line := range treeLines(buildTree(sortedKeys(secrets), folder), "") {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// this function will move (rename) a secret or a whole folder to a new secretID or folder
+// the destination must not exist, each secret is written to its new ID then deleted from the old one
+// the number of moved secrets is returned
+func MoveSecret(ctx context.Context, secstore SecretStore, from, to string) (int, error) {
+	moves := make(map[string]string)
+	if IsFolder(from) {
+		src, dst := cleanFolder(from), cleanFolder(to)
+		if src == "" {
+			return 0, fmt.Errorf("the root folder can not be moved")
+		}
+		if strings.HasPrefix(dst, src) {
+			return 0, fmt.Errorf("folder %s can not be moved into itself", src)
+		}
+		ids, err := listSecretIDs(ctx, secstore, src)
+		if err != nil {
+			return 0, err
+		}
+		for _, id := range ids {
+			moves[id] = dst + strings.TrimPrefix(id, src)
+		}
+	} else {
+		src, err := CleanSecretID(from)
+		if err != nil {
+			return 0, err
+		}
+		dst := cleanFolder(to)
+		// moving a secret into a folder keep its name
+		if !IsFolder(to) {
+			if dst, err = CleanSecretID(to); err != nil {
+				return 0, err
+			}
+		} else {
+			parts := strings.Split(src, "/")
+			dst += parts[len(parts)-1]
+		}
+		moves[src] = dst
+	}
+	//check nothing is overwritten before moving anything
+	for src, dst := range moves {
+		if CheckSecretID(ctx, secstore, dst) {
+			return 0, fmt.Errorf("Secret ID: %s already exist, %s not moved", dst, src)
+		}
+	}
+	count := 0
+	for src, dst := range moves {
+		sec, found, err := readSecret(ctx, secstore, src)
+		if err != nil {
+			return count, err
+		}
+		if !found {
+			continue
+		}
+		if err := AddSecret(ctx, secstore, sec, dst); err != nil {
+			return count, err
+		}
+		if err := DeleteSecret(ctx, secstore, src); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("nothing found to move at %s", from)
+	}
+	return count, nil
+}
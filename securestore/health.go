@@ -0,0 +1,180 @@
+package securestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/crypto"
+	"github.com/abruno06/myvault/secret"
+)
+
+// the issues of the health report
+const (
+	HealthInvalid       = "invalid"        // the entry is not a valid secret
+	HealthReused        = "reused"         // the credential is used by other secrets
+	HealthWeak          = "weak"           // the credential strength is below the minimum
+	HealthEmptyUsername = "empty username" // a login without username
+	HealthEmptyURL      = "empty url"      // a login without url
+	HealthDuplicate     = "duplicate"      // other secrets have the same url and username
+	HealthNeverUpdated  = "never updated"  // no LastUpdate, or a single version older than HealthStaleAge
+)
+
+// a secret with a single version older than this is reported as never updated
+const HealthStaleAge = 365 * 24 * time.Hour
+
+// HealthIssue is a problem found on a secret
+type HealthIssue struct {
+	SecretID string `json:"id"`
+	Kind     string `json:"kind"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// HealthReport is the result of the scan of the secrets of an application
+// Score is the percentage of secrets without issue
+type HealthReport struct {
+	Appname   string         `json:"app"`
+	Generated time.Time      `json:"generated"`
+	Secrets   int            `json:"secrets"`
+	Score     int            `json:"score"`
+	Counts    map[string]int `json:"counts"`
+	Issues    []HealthIssue  `json:"issues"`
+}
+
+// this function will scan all the secrets of the application and return their issues
+func HealthCheck(ctx context.Context, secstore SecretStore, now time.Time) (HealthReport, error) {
+	report := HealthReport{Appname: secstore.Appname, Generated: now, Counts: map[string]int{}, Issues: []HealthIssue{}}
+	ids, err := listSecretIDs(ctx, secstore, "")
+	if err != nil {
+		return report, err
+	}
+	sort.Strings(ids)
+	secrets := make(map[string]secret.Secret)
+	for _, id := range ids {
+		data, _, err := secstore.Backend.Get(ctx, secretPath(secstore, id), 0)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Secrets++
+		s, ok := secret.ConvertToSecret(data)
+		if !ok {
+			report.add(id, HealthInvalid, "ConvertToSecret failed")
+			continue
+		}
+		secrets[id] = s
+	}
+	minScore := max(config.ReadPasswordMinScore(), crypto.StrengthFair)
+	credentials := make(map[string][]string)
+	logins := make(map[string][]string)
+	for _, id := range ids {
+		s, ok := secrets[id]
+		if !ok {
+			continue
+		}
+		if s.TypeName() == secret.TypeLogin {
+			if s.Username == "" {
+				report.add(id, HealthEmptyUsername, "")
+			}
+			if s.URL == "" {
+				report.add(id, HealthEmptyURL, "")
+			}
+			if s.Username != "" && s.URL != "" {
+				key := strings.ToLower(strings.TrimSuffix(s.URL, "/")) + "\x00" + s.Username
+				logins[key] = append(logins[key], id)
+			}
+			if s.Credential != "" {
+				credentials[s.Credential] = append(credentials[s.Credential], id)
+				if strength := crypto.EstimateStrength(s.Credential, s.Username, s.URL); strength.Score < minScore {
+					report.add(id, HealthWeak, fmt.Sprintf("strength %d/4 (%s)", strength.Score, crypto.StrengthNames[strength.Score]))
+				}
+			}
+		}
+		if stale, detail := neverUpdated(ctx, secstore, id, s, now); stale {
+			report.add(id, HealthNeverUpdated, detail)
+		}
+	}
+	report.addGroups(credentials, HealthReused, "same credential as")
+	report.addGroups(logins, HealthDuplicate, "same url and username as")
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].SecretID < report.Issues[j].SecretID
+	})
+	report.Score = 100
+	if report.Secrets > 0 {
+		withIssue := make(map[string]bool)
+		for _, issue := range report.Issues {
+			withIssue[issue.SecretID] = true
+		}
+		report.Score = 100 * (report.Secrets - len(withIssue)) / report.Secrets
+	}
+	return report, nil
+}
+
+// add an issue to the report
+func (r *HealthReport) add(secretID, kind, detail string) {
+	r.Issues = append(r.Issues, HealthIssue{SecretID: secretID, Kind: kind, Detail: detail})
+	r.Counts[kind]++
+}
+
+// add an issue to every secret of the groups with more than one secret
+func (r *HealthReport) addGroups(groups map[string][]string, kind, detail string) {
+	for _, ids := range groups {
+		if len(ids) < 2 {
+			continue
+		}
+		for _, id := range ids {
+			var others []string
+			for _, other := range ids {
+				if other != id {
+					others = append(others, other)
+				}
+			}
+			r.add(id, kind, detail+" "+strings.Join(others, ", "))
+		}
+	}
+}
+
+// check if the secret was never updated: no LastUpdate, or a single version older than HealthStaleAge
+func neverUpdated(ctx context.Context, secstore SecretStore, secretID string, s secret.Secret, now time.Time) (bool, string) {
+	if s.LastUpdate.IsZero() {
+		return true, "no LastUpdate"
+	}
+	if !secstore.Backend.Versioned() || now.Sub(s.LastUpdate) < HealthStaleAge {
+		return false, ""
+	}
+	versions, err := secstore.Backend.Versions(ctx, secretPath(secstore, secretID))
+	if err != nil || len(versions) != 1 {
+		return false, ""
+	}
+	return true, "single version from " + s.LastUpdate.UTC().Format("2006-01-02")
+}
+
+// this function display the health report in tabular format
+func DisplayHealthReport(report HealthReport) {
+	fmt.Printf("Health of %s: %d%% of %d secret(s) without issue\n", report.Appname, report.Score, report.Secrets)
+	if len(report.Issues) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	format := "%s\t%s\t%s\n"
+	fmt.Fprintf(w, format, "ID", "Issue", "Detail")
+	for _, issue := range report.Issues {
+		fmt.Fprintf(w, format, issue.SecretID, issue.Kind, issue.Detail)
+	}
+	w.Flush()
+}
+
+// this function will return the health report as JSON
+func HealthReportJSON(report HealthReport) (string, error) {
+	jsonData, err := json.MarshalIndent(report, "", "  ")
+	return string(jsonData), err
+}
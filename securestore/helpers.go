@@ -2,123 +2,98 @@ package securestore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
-	"sort"
-	"strings"
-	"text/tabwriter"
 
 	"github.com/abruno06/myvault/secret"
-
-	"github.com/hashicorp/vault-client-go"
-	"github.com/hashicorp/vault-client-go/schema"
 )
 
 // this function list all secrets in vault for the given mountpath and readAPPNAME() and display them in tabuuar format
 func ListSecrets(ctx context.Context, secstore SecretStore) error {
-	//read the secret for the readAPPNAME()
-
-	Data, err := getAllSecrets(ctx, secstore)
-
-	//display all secrets found in tabular format
-	// Create a tabwriter
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-
-	columns := secret.SecretFieldNames
-	//create the format
-	format := "%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
-	//print the header
-	fmt.Fprintf(w, format, "ID", columns[0], columns[1], columns[2], columns[3], columns[4], columns[5])
-	//print the data
-	//make data order in alphabetical order
-
-	var keys []string
-	for key := range Data {
-		keys = append(keys, key)
-	}
-	// Sort the keys case-insensitively
-	sort.Slice(keys, func(i, j int) bool {
-		return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
-	})
-
-	for _, k := range keys {
-		var ok bool
-		v := Data[k]
-		s, ok := secret.ConvertToSecret(v.(map[string]interface{}))
-		if ok {
-			fmt.Fprintf(w, format, k, s.Username, s.Credential, s.URL, s.LastUpdate.UTC().Format("2006-01-02 15:04:05"), s.LastUpdateBy, s.Comment)
-
-		}
-	}
-	w.Flush()
-	return err
-
+	//list the whole appname folder
+	return ListFolder(ctx, secstore, "")
 }
 
 // this function add a Secret to vault for the given secstore and secretID
+// the write is based on the version currently stored, a *ConflictError is returned if it changes in between
 func AddSecret(ctx context.Context, secstore SecretStore, secret secret.Secret, secretID string) error {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-	//extract the appname from the SecretStore
-	appname := secstore.Appname
-	//read the secret for the readAPPNAME()
-
-	s, err := client.Secrets.KvV2Read(ctx, appname, vault.WithMountPath(mountpath))
+	//a secretID can not be a folder
+	if id, err := CleanSecretID(secretID); err != nil || id != secretID {
+		return fmt.Errorf("Secret ID: %q is not valid", secretID)
+	}
+	//read the current version of the secret entry
+	_, version, _, err := readSecretVersion(ctx, secstore, secretID)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//set the secret
-	s.Data.Data[secretID] = secret
+	return UpdateSecret(ctx, secstore, secret, secretID, version)
+}
 
-	_, err = client.Secrets.KvV2Write(ctx, appname, schema.KvV2WriteRequest{
-		Data: s.Data.Data,
-	},
-		vault.WithMountPath(mountpath))
-	if err != nil {
+// this function will return the secret and the version it was read at, the version is to be given back to UpdateSecret
+func GetSecretVersion(ctx context.Context, secstore SecretStore, secretID string) (secret.Secret, int64, error) {
+	s, version, found, err := readSecretVersion(ctx, secstore, secretID)
+	if err == nil && !found {
+		fmt.Printf("Secret ID: %s not found\n", secretID)
+	}
+	return s, version, err
+}
+
+// this function will write the secret only if the stored version is still the given version
+// a *ConflictError is returned if someone else wrote the secret in between
+func UpdateSecret(ctx context.Context, secstore SecretStore, secret secret.Secret, secretID string, version int64) error {
+	err := setSecret(ctx, secstore, secretID, secret, version)
+	if err != nil && !IsConflict(err) && !errors.Is(err, ErrReadOnly) {
 		log.Fatal(err)
 	}
 	return err
 }
 
-// this function will update a secret in vault for a given secstore, secret and secretID
+// this function will delete a secret in vault for a given secstore and secretID
+// only the version read is deleted, a *ConflictError is returned if a newer version was written in between and the
+// version read is restored
 func DeleteSecret(ctx context.Context, secstore SecretStore, secretId string) error {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-	//extract the appname from the SecretStore
-	appname := secstore.Appname
-	//read the secret for the readAPPNAME()
-	s, err := client.Secrets.KvV2Read(ctx, appname, vault.WithMountPath(mountpath))
+	//read the current version of the secret entry
+	sec, version, found, err := readSecretVersion(ctx, secstore, secretId)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//delete the secret
-	delete(s.Data.Data, secretId)
-
-	_, err = client.Secrets.KvV2Write(ctx, appname, schema.KvV2WriteRequest{
-		Data: s.Data.Data,
-	},
-		vault.WithMountPath(mountpath))
+	if !found {
+		return fmt.Errorf("Secret ID: %s not found", secretId)
+	}
+	//delete the version read
+	path := secretPath(secstore, secretId)
+	err = secstore.Backend.Delete(ctx, path, version)
+	if errors.Is(err, ErrReadOnly) {
+		return err
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+	//a newer version written meanwhile is left untouched
+	current, err := currentVersion(ctx, secstore, path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if current != version {
+		if err := secstore.Backend.Undelete(ctx, path, version); err != nil {
+			log.Fatal(err)
+		}
+		return &ConflictError{SecretID: secretId, Version: version}
+	}
+	//without versions the delete is permanent, the chunks of the attachments go with it
+	if !secstore.Backend.Versioned() {
+		return destroyOrphanChunks(ctx, secstore, secretId, sec.Attachments)
+	}
 	return err
 }
 
 // check if SecretId already exist in vault
 func CheckSecretID(ctx context.Context, secstore SecretStore, secretID string) bool {
-	//read the secret for the readAPPNAME()
-	s, err := secstore.Client.Secrets.KvV2Read(ctx, secstore.Appname, vault.WithMountPath(secstore.Mountpath))
+	//read the secret entry
+	_, found, err := readSecret(ctx, secstore, secretID)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//delete the secret
-	if s.Data.Data[secretID] != nil {
-		return true
-	}
-	return false
+	return found
 }
@@ -0,0 +1,136 @@
+package securestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/secret"
+)
+
+// SecretVersion describe one kv v2 version of a secret entry
+type SecretVersion struct {
+	SecretID     string
+	Version      int64
+	CreatedTime  time.Time
+	DeletionTime string
+	Destroyed    bool
+}
+
+// this function will return all the versions of a secretID, oldest first
+func ListSecretVersions(ctx context.Context, secstore SecretStore, secretID string) ([]SecretVersion, error) {
+	versions, err := secstore.Backend.Versions(ctx, secretPath(secstore, secretID))
+	if err != nil {
+		return nil, err
+	}
+	for i := range versions {
+		versions[i].SecretID = secretID
+	}
+	return versions, nil
+}
+
+// sort the versions, oldest first
+func sortVersions(versions []SecretVersion) {
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+}
+
+// this function will return the versions of every secret of the appname, most recent first
+func ListAppVersions(ctx context.Context, secstore SecretStore) ([]SecretVersion, error) {
+	keys, err := listSecretIDs(ctx, secstore, "")
+	if err != nil {
+		return nil, err
+	}
+	var rValue []SecretVersion
+	for _, k := range keys {
+		versions, err := ListSecretVersions(ctx, secstore, k)
+		if err != nil {
+			return nil, err
+		}
+		rValue = append(rValue, versions...)
+	}
+	sort.Slice(rValue, func(i, j int) bool {
+		return rValue[i].CreatedTime.After(rValue[j].CreatedTime)
+	})
+	return rValue, nil
+}
+
+// this function will display the versions of the secretID (or of the whole appname if secretID is empty) in tabular format
+func ListSecretHistory(ctx context.Context, secstore SecretStore, secretID string) error {
+	var versions []SecretVersion
+	var err error
+	if secretID == "" {
+		versions, err = ListAppVersions(ctx, secstore)
+	} else {
+		versions, err = ListSecretVersions(ctx, secstore, secretID)
+	}
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	format := "%s\t%s\t%s\t%s\n"
+	fmt.Fprintf(w, format, "ID", "Version", "Created", "State")
+	for _, v := range versions {
+		state := "active"
+		if v.Destroyed {
+			state = "destroyed"
+		} else if v.DeletionTime != "" {
+			state = "deleted " + v.DeletionTime
+		}
+		fmt.Fprintf(w, format, v.SecretID, strconv.FormatInt(v.Version, 10), v.CreatedTime.UTC().Format("2006-01-02 15:04:05"), state)
+	}
+	w.Flush()
+	return nil
+}
+
+// this function will return the secret as it was at the given version
+func GetSecretAtVersion(ctx context.Context, secstore SecretStore, secretID string, version int64) (secret.Secret, error) {
+	data, _, err := secstore.Backend.Get(ctx, secretPath(secstore, secretID), version)
+	if errors.Is(err, ErrNotFound) {
+		return secret.Secret{}, fmt.Errorf("Secret ID: %s version %d has no data", secretID, version)
+	}
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	rValue, ok := secret.ConvertToSecret(data)
+	if !ok {
+		return secret.Secret{}, fmt.Errorf("Secret ID: %s version %d not valid secret", secretID, version)
+	}
+	return rValue, nil
+}
+
+// this function will return the field changes of the secretID between two versions
+func DiffSecretVersions(ctx context.Context, secstore SecretStore, secretID string, from, to int64, showCredential bool) ([]secret.FieldChange, error) {
+	oldSecret, err := GetSecretAtVersion(ctx, secstore, secretID, from)
+	if err != nil {
+		return nil, err
+	}
+	newSecret, err := GetSecretAtVersion(ctx, secstore, secretID, to)
+	if err != nil {
+		return nil, err
+	}
+	return secret.Diff(oldSecret, newSecret, showCredential), nil
+}
+
+// this function will write back the value the secretID had at the given version as a new version
+// the other secrets of the appname are not touched
+func RestoreSecretVersion(ctx context.Context, secstore SecretStore, secretID string, version int64) error {
+	old, err := GetSecretAtVersion(ctx, secstore, secretID, version)
+	if err != nil {
+		return err
+	}
+	current, err := currentVersion(ctx, secstore, secretPath(secstore, secretID))
+	if err != nil {
+		return err
+	}
+	old.LastUpdate = time.Now()
+	old.LastUpdateBy = config.User
+	return setSecret(ctx, secstore, secretID, old, current)
+}
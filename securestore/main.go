@@ -3,108 +3,135 @@ package securestore
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/abruno06/myvault/secret"
 
 	"github.com/hashicorp/vault-client-go"
-	"github.com/hashicorp/vault-client-go/schema"
 )
 
+// SecretStore give access to the secrets of an appname stored in a Backend
+// Client is only set when connected to vault, it is needed by the token functions
+// Namespace is the vault enterprise namespace of the Client, empty for the root namespace
 type SecretStore struct {
 	Client    *vault.Client
+	Namespace string
 	Mountpath string
 	Appname   string
+	Backend   Backend
 }
 
-func GetSecret(ctx context.Context, secstore SecretStore, secretID string) (secret.Secret, error) {
-	// extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-	appname := secstore.Appname
-	rValue := secret.Secret{}
-	//read the secret for the readAPPNAME()
-	s, err := client.Secrets.KvV2Read(ctx, appname, vault.WithMountPath(mountpath))
-
-	if err == nil {
-
-		vValue := s.Data.Data
-		if vValue[secretID] != nil {
-			var ok bool
-			rValue, ok = secret.ConvertToSecret(vValue[secretID].(map[string]interface{}))
-			if !ok {
-				log.Printf("Secret ID: %s not valid secret\n", vValue[secretID])
-				log.Printf("Secret ID: is type %T \n", vValue[secretID])
-				rValue = secret.Secret{}
-				err = fmt.Errorf("Secret ID: %s not valid secret", vValue[secretID])
-			}
-			//fmt.Printf("Secret: %v\n", rValue)
-		} else {
-			fmt.Printf("Secret ID: %s not found\n", secretID)
-			rValue = secret.Secret{}
-		}
+// return the path where the given secretID is stored (one entry per secret under the appname)
+func secretPath(secstore SecretStore, secretID string) string {
+	return secstore.Appname + "/" + secretID
+}
+
+// this function will read a single secret entry from the store
+// found is false when the secretID does not exist (or its latest version is deleted)
+func readSecret(ctx context.Context, secstore SecretStore, secretID string) (secret.Secret, bool, error) {
+	rValue, _, found, err := readSecretVersion(ctx, secstore, secretID)
+	return rValue, found, err
+}
 
+// same as readSecret but also return the version that was read
+// when the secretID is not found the version is the one a check-and-set write must use to create it
+func readSecretVersion(ctx context.Context, secstore SecretStore, secretID string) (secret.Secret, int64, bool, error) {
+	data, version, err := secstore.Backend.Get(ctx, secretPath(secstore, secretID), 0)
+	if errors.Is(err, ErrNotFound) {
+		version, err := currentVersion(ctx, secstore, secretPath(secstore, secretID))
+		return secret.Secret{}, version, false, err
 	}
-	return rValue, err
+	if err != nil {
+		return secret.Secret{}, 0, false, err
+	}
+	rValue, ok := secret.ConvertToSecret(data)
+	if !ok {
+		return secret.Secret{}, version, true, fmt.Errorf("Secret ID: %s not valid secret", secretID)
+	}
+	return rValue, version, true, nil
 }
 
-// this function will connect to vault and return all secret for a given mountpath, APPNAME
-func getAllSecrets(ctx context.Context, secstore SecretStore) (map[string]interface{}, error) {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-	//extract the appname from the SecretStore
-	appname := secstore.Appname
-	//read the secret for the readAPPNAME()
-	s, err := client.Secrets.KvV2Read(ctx, appname, vault.WithMountPath(mountpath))
+// this function will list all secretID stored under the appname, sub folders are walked recursively
+func listSecretIDs(ctx context.Context, secstore SecretStore, folder string) ([]string, error) {
+	keys, err := secstore.Backend.List(ctx, secstore.Appname+"/"+folder)
+	if errors.Is(err, ErrNotFound) {
+		return []string{}, nil
+	}
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	var rValue []string
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			sub, err := listSecretIDs(ctx, secstore, folder+key)
+			if err != nil {
+				return nil, err
+			}
+			rValue = append(rValue, sub...)
+			continue
+		}
+		rValue = append(rValue, folder+key)
 	}
-	return s.Data.Data, err
+	return rValue, nil
 }
 
-// this function will add a secret to vault for a given SecretStore, secret and secretID
-func setSecret(ctx context.Context, secstore SecretStore, secretID string, secret secret.Secret) error {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-	//extract the appname from the SecretStore
-	appname := secstore.Appname
-	//read the secret for the readAPPNAME()
-	s, err := client.Secrets.KvV2Read(ctx, appname, vault.WithMountPath(mountpath))
+func GetSecret(ctx context.Context, secstore SecretStore, secretID string) (secret.Secret, error) {
+	//read the secret entry
+	rValue, found, err := readSecret(ctx, secstore, secretID)
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("Secret ID: %s not valid secret\n", secretID)
+		return secret.Secret{}, err
+	}
+	if !found {
+		fmt.Printf("Secret ID: %s not found\n", secretID)
 	}
-	//ask the Secret detail
-	s.Data.Data[secretID] = secret
+	return rValue, err
+}
 
-	_, err = client.Secrets.KvV2Write(ctx, appname, schema.KvV2WriteRequest{
-		Data: s.Data.Data,
-	},
-		vault.WithMountPath(mountpath))
+// this function will return all secret for a given mountpath, APPNAME
+func getAllSecrets(ctx context.Context, secstore SecretStore) (map[string]secret.Secret, error) {
+	//list the secret entries of the appname
+	keys, err := listSecretIDs(ctx, secstore, "")
 	if err != nil {
 		log.Fatal(err)
 	}
+	rValue := make(map[string]secret.Secret)
+	for _, k := range keys {
+		s, found, err := readSecret(ctx, secstore, k)
+		if err != nil {
+			log.Printf("Secret ID: %s skipped: %v\n", k, err)
+			continue
+		}
+		if found {
+			rValue[k] = s
+		}
+	}
+	return rValue, err
+}
+
+// this function will add a secret to the store for a given SecretStore, secret and secretID
+// the write is a check-and-set on version, a *ConflictError is returned if the entry changed since that version
+func setSecret(ctx context.Context, secstore SecretStore, secretID string, s secret.Secret, version int64) error {
+	//write the secret into its own entry
+	_, err := secstore.Backend.Put(ctx, secretPath(secstore, secretID), secret.ConvertFromSecret(s), version)
+	if errors.Is(err, ErrVersionMismatch) {
+		return &ConflictError{SecretID: secretID, Version: version}
+	}
 	return err
 }
 
 // this function will set a cubyhole for the list of secretsId
 func setCubbyholeList(ctx context.Context, secstore SecretStore, storePath string, s map[string]secret.Secret) error {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
 	// Add secretId as a key
 	combinedData := make(map[string]interface{})
 	for k, v := range s {
 		combinedData[k] = secret.ConvertFromSecret(v)
 	}
-	_, err := client.Secrets.CubbyholeWrite(ctx, storePath, combinedData, vault.WithMountPath(mountpath))
+	err := secstore.Backend.WriteCubbyhole(ctx, storePath, combinedData)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -113,16 +140,12 @@ func setCubbyholeList(ctx context.Context, secstore SecretStore, storePath strin
 
 // this function will set a cubbyhole for the given secretID
 func setCubbyhole(ctx context.Context, secstore SecretStore, secretId string, s secret.Secret) error {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
 	data := secret.ConvertFromSecret(s)
 	//fmt.Printf("data: %v\n", data)
 	// Add secretId as a key
 	combinedData := make(map[string]interface{})
 	combinedData[secretId] = data
-	_, err := client.Secrets.CubbyholeWrite(ctx, secretId, combinedData, vault.WithMountPath(mountpath))
+	err := secstore.Backend.WriteCubbyhole(ctx, secretId, combinedData)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -131,15 +154,10 @@ func setCubbyhole(ctx context.Context, secstore SecretStore, secretId string, s
 
 // this function will set a cubyhole for the list of secretsId
 func SetServiceSecretCubbyhole(ctx context.Context, secstore SecretStore, s map[string]secret.Secret) error {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-
 	var err error
 	for k, v := range s {
 		item := secret.ConvertFromSecret(v)
-		_, err = client.Secrets.CubbyholeWrite(ctx, k, item, vault.WithMountPath(mountpath))
+		err = secstore.Backend.WriteCubbyhole(ctx, k, item)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -150,39 +168,26 @@ func SetServiceSecretCubbyhole(ctx context.Context, secstore SecretStore, s map[
 
 // take a cubbyhole and wrap the secret and return the wrapped token
 func WrapCubbyhole(ctx context.Context, secstore SecretStore, path string, ttl time.Duration) (string, error) {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-	//read the cubbyhole
-	resp, err := client.Secrets.CubbyholeRead(ctx, path, vault.WithMountPath(mountpath), vault.WithResponseWrapping(ttl))
+	//wrap the cubbyhole entry
+	token, err := secstore.Backend.Wrap(ctx, path, ttl)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//fmt.Printf("Wrapped response: %v\n", resp.WrapInfo)
-	return resp.WrapInfo.Token, err
+	return token, err
 }
 
 // create a wrap secret for a given appname and return the token
 func WrapSecret(ctx context.Context, secstore SecretStore, secretID string, ttl time.Duration) (string, error) {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-	//extract the appname from the SecretStore
-	appname := secstore.Appname
-	//read the secret for the readAPPNAME()
-	s, err := client.Secrets.KvV2Read(ctx, appname, vault.WithMountPath(mountpath))
+	//read the secret entry
+	sec, found, err := readSecret(ctx, secstore, secretID)
 	if err != nil {
 		log.Fatal(err)
 	}
 	//check if secretID exist
-	if s.Data.Data[secretID] == nil {
+	if !found {
 		log.Fatalf("Secret ID: %s not found\n", secretID)
 		return "", fmt.Errorf("Secret ID: %s not found\n", secretID)
 	}
-	//convert to secret
-	sec, _ := secret.ConvertToSecret(s.Data.Data[secretID].(map[string]interface{}))
 	//put the secret into the cubbyhole
 	err = setCubbyhole(ctx, secstore, secretID, sec)
 	if err != nil {
@@ -194,28 +199,24 @@ func WrapSecret(ctx context.Context, secstore SecretStore, secretID string, ttl
 }
 
 // this function take a list of secretId and wrap the cubbyhole and return the token
+// a folder (ending with "/") in the list add all the secrets of the folder
 func WrapSecretList(ctx context.Context, secstore SecretStore, secList []string, storePath string, ttl time.Duration) (string, error) {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-	//extract the appname from the SecretStore
-	appname := secstore.Appname
-
-	//read the secret for the readAPPNAME()
-	s, err := client.Secrets.KvV2Read(ctx, appname, vault.WithMountPath(mountpath))
+	//the folders of the list are replaced by their secrets
+	secList, err := ExpandSecretIDs(ctx, secstore, secList)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 	chValue := make(map[string]secret.Secret)
 	for _, secretID := range secList {
+		sec, found, err := readSecret(ctx, secstore, secretID)
+		if err != nil {
+			log.Fatal(err)
+		}
 		//check if secretID exist
-		if s.Data.Data[secretID] == nil {
+		if !found {
 			fmt.Printf("Secret ID: %s not found\n", secretID)
 			continue
 		}
-		//convert to secret
-		sec, _ := secret.ConvertToSecret(s.Data.Data[secretID].(map[string]interface{}))
 		chValue[secretID] = sec
 	}
 	err = setCubbyholeList(ctx, secstore, storePath, chValue)
@@ -227,18 +228,14 @@ func WrapSecretList(ctx context.Context, secstore SecretStore, secList []string,
 
 // this function will unwrap a cubbyhole and return the secret
 func UnWrappeSecret(ctx context.Context, secstore SecretStore, token string) (map[string]secret.Secret, error) {
-	//extract the client from the SecretStore
-	client := secstore.Client
-
 	//read the cubbyhole
-	resp, err := vault.Unwrap[map[string]interface{}](ctx, client, token)
+	data, err := secstore.Backend.Unwrap(ctx, token)
 	if err != nil {
 		log.Fatal(err)
 	}
 	rValue := make(map[string]secret.Secret)
-	//fmt.Printf("Unwrapped response: %v\n", resp)
-	// loop to the resp.Data key
-	for key, v := range resp.Data {
+	// loop to the data key
+	for key, v := range data {
 		rValue[key], _ = secret.ConvertToSecret(v.(map[string]interface{}))
 	}
 	//convert to secret
@@ -0,0 +1,117 @@
+package securestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/abruno06/myvault/secret"
+)
+
+// The legacy layout stored every secret of the appname in a single kv entry <MOUNTPATH>/data/<APPNAME>
+// the current layout store each secretID in its own entry <MOUNTPATH>/data/<APPNAME>/<secretID>
+
+// convert a legacy entry to a Secret
+// older versions wrote the Secret struct directly so the keys may use the json tags (username, credential...)
+func legacyToSecret(v interface{}) (secret.Secret, bool) {
+	object, ok := v.(map[string]interface{})
+	if !ok {
+		return secret.Secret{}, false
+	}
+	if s, ok := secret.ConvertToSecret(object); ok {
+		return s, true
+	}
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return secret.Secret{}, false
+	}
+	var s secret.Secret
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return secret.Secret{}, false
+	}
+	return s, s.Username != "" || s.Credential != ""
+}
+
+// this function will split the legacy appname entry into one entry per secretID
+// existing per secret entries are kept untouched, the legacy entry is soft deleted so it can be recovered
+// return the number of migrated secrets
+func MigrateToSplit(ctx context.Context, secstore SecretStore) (int, error) {
+	appname := secstore.Appname
+	//read the legacy entry
+	data, version, err := secstore.Backend.Get(ctx, appname, 0)
+	if errors.Is(err, ErrNotFound) {
+		return 0, fmt.Errorf("no legacy entry found at %s/%s", secstore.Mountpath, appname)
+	}
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for secretID, v := range data {
+		sec, ok := legacyToSecret(v)
+		if !ok {
+			fmt.Printf("Secret ID: %s not valid secret, skipped\n", secretID)
+			continue
+		}
+		_, secVersion, found, err := readSecretVersion(ctx, secstore, secretID)
+		if err != nil {
+			return count, err
+		}
+		if found {
+			fmt.Printf("Secret ID: %s already exist, skipped\n", secretID)
+			continue
+		}
+		if err := setSecret(ctx, secstore, secretID, sec, secVersion); err != nil {
+			return count, err
+		}
+		fmt.Printf("Secret ID: %s migrated\n", secretID)
+		count++
+	}
+	//without versions deleting the legacy entry could not be undone, it is kept
+	if !secstore.Backend.Versioned() {
+		fmt.Printf("Legacy entry %s/%s kept, delete it once the migration is checked\n", secstore.Mountpath, appname)
+		return count, nil
+	}
+	//soft delete the legacy entry, its versions stay available for a rollback
+	return count, secstore.Backend.Delete(ctx, appname, version)
+}
+
+// this function will merge all the per secret entries back into the legacy appname entry
+// the per secret entries are soft deleted once the legacy entry is written
+// return the number of secrets written back
+func RollbackToBlob(ctx context.Context, secstore SecretStore) (int, error) {
+	appname := secstore.Appname
+	keys, err := listSecretIDs(ctx, secstore, "")
+	if err != nil {
+		return 0, err
+	}
+	data := make(map[string]interface{})
+	versions := make(map[string]int64)
+	for _, secretID := range keys {
+		sec, version, found, err := readSecretVersion(ctx, secstore, secretID)
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			data[secretID] = secret.ConvertFromSecret(sec)
+			versions[secretID] = version
+		}
+	}
+	version, err := currentVersion(ctx, secstore, appname)
+	if err != nil {
+		return 0, err
+	}
+	_, err = secstore.Backend.Put(ctx, appname, data, version)
+	if errors.Is(err, ErrVersionMismatch) {
+		return 0, fmt.Errorf("%s/%s was modified during the rollback", secstore.Mountpath, appname)
+	}
+	if err != nil {
+		return 0, err
+	}
+	for secretID, version := range versions {
+		if err := secstore.Backend.Delete(ctx, secretPath(secstore, secretID), version); err != nil {
+			return len(data), err
+		}
+	}
+	return len(data), nil
+}
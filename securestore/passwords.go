@@ -0,0 +1,66 @@
+package securestore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/crypto"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// the prefix of a policy name selecting a vault password policy instead of a local one
+const VaultPolicyPrefix = "vault:"
+
+// this function will generate a credential with the password policy name
+// "vault:<name>" is a vault password policy (sys/policies/password), the other names are local policies (PASSWORDPOLICIES)
+// an empty name is VAULTPASSWORDPOLICY when it is set for the application, PASSWORDPOLICY otherwise
+// the local generator is used when the store is not vault or the token is not permitted to use the vault policy
+func GeneratePassword(ctx context.Context, secstore SecretStore, name string) (string, error) {
+	if name == "" {
+		if vaultPolicy := config.ReadVaultPasswordPolicy(); vaultPolicy != "" {
+			name = VaultPolicyPrefix + vaultPolicy
+		}
+	}
+	if vaultPolicy, isVault := strings.CutPrefix(name, VaultPolicyPrefix); isVault {
+		if secstore.Client == nil {
+			log.Printf("Vault password policy %s needs the vault backend, using the local generator\n", vaultPolicy)
+			return GenerateLocalPassword("")
+		}
+		password, err := GenerateVaultPassword(ctx, secstore, vaultPolicy)
+		if vault.IsErrorStatus(err, http.StatusForbidden) {
+			log.Printf("Vault password policy %s is not permitted, using the local generator\n", vaultPolicy)
+			return GenerateLocalPassword("")
+		}
+		return password, err
+	}
+	return GenerateLocalPassword(name)
+}
+
+// this function will generate a credential with the vault password policy
+func GenerateVaultPassword(ctx context.Context, secstore SecretStore, name string) (string, error) {
+	resp, err := secstore.Client.System.PoliciesGeneratePasswordFromPasswordPolicy(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if resp.Data.Password == "" {
+		return "", fmt.Errorf("vault password policy %s returned no password", name)
+	}
+	return resp.Data.Password, nil
+}
+
+// this function will generate a credential with the local password policy name, an empty name is PASSWORDPOLICY
+func GenerateLocalPassword(name string) (string, error) {
+	if name == "" {
+		name = config.ReadPasswordPolicy()
+	}
+	policy, err := crypto.LookupPolicy(name, config.ReadPasswordPolicies())
+	if err != nil {
+		return "", err
+	}
+	return crypto.GeneratePassword(policy)
+}
@@ -0,0 +1,157 @@
+package securestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/secret"
+)
+
+// A secret must be rotated MaxAge after its LastUpdate, the MaxAge of the secret is used first, then the
+// shortest one of its tags (config ROTATIONTAGS) and then the application default (config ROTATIONMAXAGE)
+
+// the status of a secret in the rotation report
+// an unreadable secret can not be checked, it is reported so it does not silently disappear
+const (
+	RotationOverdue    = "overdue"
+	RotationDueSoon    = "due soon"
+	RotationUnreadable = "unreadable"
+)
+
+// RotationEntry is a secret due for rotation
+type RotationEntry struct {
+	SecretID   string    `json:"id"`
+	LastUpdate time.Time `json:"lastupdate"`
+	MaxAge     string    `json:"maxage"`
+	// Policy is where the max age comes from: secret, tag:<name> or default
+	Policy string    `json:"policy"`
+	DueAt  time.Time `json:"due"`
+	Status string    `json:"status"`
+}
+
+// RotationPolicy is the default and per tag max ages used when a secret has no MaxAge
+type RotationPolicy struct {
+	Default string
+	Tags    map[string]string
+	// Warning is how long before their due date the secrets are reported
+	Warning string
+}
+
+// return the rotation policy of the configuration
+func ReadRotationPolicy() RotationPolicy {
+	return RotationPolicy{Default: config.ReadRotationMaxAge(), Tags: config.ReadRotationTags(), Warning: config.ReadRotationWarning()}
+}
+
+// return the max age of the secret and where it comes from, an empty max age means no rotation
+func (p RotationPolicy) maxAge(s secret.Secret) (string, string) {
+	if s.MaxAge != "" {
+		return s.MaxAge, "secret"
+	}
+	var age, policy string
+	var shortest time.Duration
+	for _, tag := range s.Tags {
+		value, ok := p.Tags[tag]
+		if !ok {
+			continue
+		}
+		duration, err := secret.ParseMaxAge(value)
+		if err != nil {
+			log.Printf("Invalid ROTATIONTAGS %s: %v\n", tag, err)
+			continue
+		}
+		if age == "" || duration < shortest {
+			age, policy, shortest = value, "tag:"+tag, duration
+		}
+	}
+	if age != "" {
+		return age, policy
+	}
+	if p.Default != "" {
+		return p.Default, "default"
+	}
+	return "", ""
+}
+
+// this function will return the entry of the secret at the given time, ok is false if it is not due before now+warning
+func (p RotationPolicy) check(secretID string, s secret.Secret, now time.Time) (RotationEntry, bool) {
+	age, policy := p.maxAge(s)
+	duration, err := secret.ParseMaxAge(age)
+	if err != nil {
+		log.Printf("Secret ID: %s %v\n", secretID, err)
+		return RotationEntry{}, false
+	}
+	if duration == 0 {
+		return RotationEntry{}, false
+	}
+	warning, err := secret.ParseMaxAge(p.Warning)
+	if err != nil {
+		log.Printf("Invalid ROTATIONWARNING: %v\n", err)
+	}
+	entry := RotationEntry{SecretID: secretID, LastUpdate: s.LastUpdate, MaxAge: age, Policy: policy, DueAt: s.LastUpdate.Add(duration)}
+	switch {
+	case !now.Before(entry.DueAt):
+		entry.Status = RotationOverdue
+	case now.Add(warning).After(entry.DueAt):
+		entry.Status = RotationDueSoon
+	default:
+		return RotationEntry{}, false
+	}
+	return entry, true
+}
+
+// this function will return the secrets overdue or due soon at the given time, the first due first
+// the secrets that can not be read come first with the status RotationUnreadable
+func RotationReport(ctx context.Context, secstore SecretStore, policy RotationPolicy, now time.Time) ([]RotationEntry, error) {
+	secrets, skipped, err := readFolderSecrets(ctx, secstore, "")
+	if err != nil {
+		return nil, err
+	}
+	rValue := []RotationEntry{}
+	for _, id := range skipped {
+		rValue = append(rValue, RotationEntry{SecretID: id, Status: RotationUnreadable})
+	}
+	for id, s := range secrets {
+		if entry, due := policy.check(id, s, now); due {
+			rValue = append(rValue, entry)
+		}
+	}
+	sort.Slice(rValue, func(i, j int) bool {
+		if rValue[i].DueAt.Equal(rValue[j].DueAt) {
+			return rValue[i].SecretID < rValue[j].SecretID
+		}
+		return rValue[i].DueAt.Before(rValue[j].DueAt)
+	})
+	return rValue, nil
+}
+
+// this function display the rotation report in tabular format
+func DisplayRotationReport(entries []RotationEntry) {
+	if len(entries) == 0 {
+		fmt.Println("No secret due for rotation")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	format := "%s\t%s\t%s\t%s\t%s\t%s\n"
+	fmt.Fprintf(w, format, "ID", "Status", "Due", "LastUpdate", "MaxAge", "Policy")
+	for _, e := range entries {
+		if e.Status == RotationUnreadable {
+			fmt.Fprintf(w, format, e.SecretID, e.Status, "", "", "", "")
+			continue
+		}
+		fmt.Fprintf(w, format, e.SecretID, e.Status, e.DueAt.UTC().Format("2006-01-02"), e.LastUpdate.UTC().Format("2006-01-02 15:04:05"), e.MaxAge, e.Policy)
+	}
+	w.Flush()
+}
+
+// this function will return the rotation report as JSON
+func RotationReportJSON(entries []RotationEntry) (string, error) {
+	jsonData, err := json.MarshalIndent(entries, "", "  ")
+	return string(jsonData), err
+}
@@ -0,0 +1,134 @@
+package securestore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/abruno06/myvault/secret"
+)
+
+// SearchResult is a secret matching a search query, the best matches have the highest Score
+type SearchResult struct {
+	SecretID string
+	Secret   secret.Secret
+	Score    int
+	// Field is the field with the best match
+	Field string
+}
+
+// the fields searched and their weight, the credential is never searched
+var searchFields = []struct {
+	name   string
+	weight int
+	value  func(id string, s secret.Secret) string
+}{
+	{"ID", 3, func(id string, s secret.Secret) string { return id }},
+	{"Username", 2, func(id string, s secret.Secret) string { return s.Username }},
+	{"URL", 2, func(id string, s secret.Secret) string { return s.URL }},
+	{"Tags", 2, func(id string, s secret.Secret) string { return strings.Join(s.Tags, " ") }},
+	{"Comment", 1, func(id string, s secret.Secret) string { return s.Comment }},
+	{"Fields", 1, typedFieldsText},
+}
+
+// return the fields of a typed secret that are neither secret nor multiline (Host, Scopes...)
+func typedFieldsText(id string, s secret.Secret) string {
+	var rValue []string
+	for _, f := range s.TypeFields() {
+		if _, ok := s.Fields[f.Name]; ok && !f.Secret && !f.Multiline {
+			rValue = append(rValue, s.Fields[f.Name])
+		}
+	}
+	return strings.Join(rValue, " ")
+}
+
+// return how well the term match the text, 0 when it does not match
+// exact match > prefix > word prefix > substring > letters in order (fuzzy)
+func matchScore(term, text string) int {
+	term, text = strings.ToLower(term), strings.ToLower(text)
+	if term == "" || text == "" {
+		return 0
+	}
+	switch {
+	case text == term:
+		return 100
+	case strings.HasPrefix(text, term):
+		return 80
+	}
+	if i := strings.Index(text, term); i >= 0 {
+		//a match at the start of a word ("db" in "prod-db") is better
+		if r, _ := utf8.DecodeLastRuneInString(text[:i]); strings.ContainsRune("/-_. @:", r) {
+			return 70
+		}
+		return 60
+	}
+	return fuzzyScore(term, text)
+}
+
+// return a score between 1 and 40 if all the letters of the term are found in order in the text
+// the score decrease with the gaps between the letters
+func fuzzyScore(term, text string) int {
+	gaps, pos := 0, 0
+	for _, r := range term {
+		i := strings.IndexRune(text[pos:], r)
+		if i < 0 {
+			return 0
+		}
+		gaps += i
+		pos += i + utf8.RuneLen(r)
+	}
+	score := 40 - gaps
+	if score < 1 {
+		score = 1
+	}
+	return score
+}
+
+// return the score of the secret for the query, every word of the query must match one field
+func scoreSecret(terms []string, id string, s secret.Secret) (int, string) {
+	total, bestField, best := 0, "", 0
+	for _, term := range terms {
+		termBest := 0
+		for _, field := range searchFields {
+			score := matchScore(term, field.value(id, s)) * field.weight
+			if score > termBest {
+				termBest = score
+			}
+			if score > best {
+				best, bestField = score, field.name
+			}
+		}
+		if termBest == 0 {
+			return 0, ""
+		}
+		total += termBest
+	}
+	return total, bestField
+}
+
+// this function will search the secrets matching the query in their ID, Username, URL, Tags, Comment and typed fields
+// the results are sorted from the best match
+func SearchSecrets(ctx context.Context, secstore SecretStore, query string) ([]SearchResult, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	secrets, err := getFolderSecrets(ctx, secstore, "")
+	if err != nil {
+		return nil, err
+	}
+	var rValue []SearchResult
+	for id, s := range secrets {
+		if score, field := scoreSecret(terms, id, s); score > 0 {
+			rValue = append(rValue, SearchResult{SecretID: id, Secret: s, Score: score, Field: field})
+		}
+	}
+	sort.Slice(rValue, func(i, j int) bool {
+		if rValue[i].Score != rValue[j].Score {
+			return rValue[i].Score > rValue[j].Score
+		}
+		return strings.ToLower(rValue[i].SecretID) < strings.ToLower(rValue[j].SecretID)
+	})
+	return rValue, nil
+}
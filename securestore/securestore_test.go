@@ -0,0 +1,1039 @@
+package securestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abruno06/myvault/crypto"
+	"github.com/abruno06/myvault/secret"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// this will allow to test the securestore functions using the memory backend
+
+// return a SecretStore using a new memory backend
+func newTestStore() SecretStore {
+	return SecretStore{Mountpath: "kv", Appname: "myapp", Backend: NewMemoryBackend()}
+}
+
+var testSecret = secret.Secret{Username: "user", Credential: "password", URL: "url", Comment: "comment", LastUpdate: time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC), LastUpdateBy: "user"}
+
+// test AddSecret, GetSecret and CheckSecretID
+func TestAddGetSecret(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	if CheckSecretID(ctx, secstore, "id1") {
+		t.Errorf("CheckSecretID() = true; want false")
+	}
+	if err := AddSecret(ctx, secstore, testSecret, "id1"); err != nil {
+		t.Fatalf("AddSecret() error %v", err)
+	}
+	if !CheckSecretID(ctx, secstore, "id1") {
+		t.Errorf("CheckSecretID() = false; want true")
+	}
+	if s, err := GetSecret(ctx, secstore, "id1"); err != nil || !s.Equal(testSecret) {
+		t.Errorf("GetSecret() = %v, %v; want %v", s, err, testSecret)
+	}
+	all, _ := getAllSecrets(ctx, secstore)
+	if len(all) != 1 {
+		t.Errorf("getAllSecrets() = %v; want 1 secret", all)
+	}
+}
+
+// test that a write based on an old version is rejected
+func TestUpdateSecretConflict(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	AddSecret(ctx, secstore, testSecret, "id1")
+	_, version, _ := GetSecretVersion(ctx, secstore, "id1")
+	//someone else update the secret
+	AddSecret(ctx, secstore, testSecret, "id1")
+	err := UpdateSecret(ctx, secstore, testSecret, "id1", version)
+	if !IsConflict(err) {
+		t.Errorf("UpdateSecret() = %v; want a ConflictError", err)
+	}
+}
+
+// a backend where another client writes a new version right before each delete
+type racingBackend struct {
+	Backend
+}
+
+func (b racingBackend) Delete(ctx context.Context, path string, version int64) error {
+	data, current, _ := b.Backend.Get(ctx, path, 0)
+	b.Backend.Put(ctx, path, data, current)
+	return b.Backend.Delete(ctx, path, version)
+}
+
+// test that a delete based on an old version is rejected and leaves the version read untouched
+func TestDeleteSecretConflict(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	AddSecret(ctx, secstore, testSecret, "id1")
+	secstore.Backend = racingBackend{secstore.Backend}
+	if err := DeleteSecret(ctx, secstore, "id1"); !IsConflict(err) {
+		t.Errorf("DeleteSecret() = %v; want a ConflictError", err)
+	}
+	if versions, _ := ListSecretVersions(ctx, secstore, "id1"); len(versions) != 2 || versions[0].DeletionTime != "" {
+		t.Errorf("ListSecretVersions() = %v; want the version read not deleted", versions)
+	}
+}
+
+// test DeleteSecret, the trash and the purge
+func TestTrash(t *testing.T) {
+	t.Setenv("TRASHRETENTION", "1h")
+	ctx := context.Background()
+	secstore := newTestStore()
+	AddSecret(ctx, secstore, testSecret, "id1")
+	if err := DeleteSecret(ctx, secstore, "id1"); err != nil {
+		t.Fatalf("DeleteSecret() error %v", err)
+	}
+	if CheckSecretID(ctx, secstore, "id1") {
+		t.Errorf("CheckSecretID() = true after delete; want false")
+	}
+	if entries, err := ListTrash(ctx, secstore); err != nil || len(entries) != 1 || entries[0].SecretID != "id1" {
+		t.Errorf("ListTrash() = %v, %v; want id1", entries, err)
+	}
+	if err := UndeleteSecret(ctx, secstore, "id1"); err != nil || !CheckSecretID(ctx, secstore, "id1") {
+		t.Errorf("UndeleteSecret() = %v; want id1 restored", err)
+	}
+	DeleteSecret(ctx, secstore, "id1")
+	if err := PurgeSecret(ctx, secstore, "id1"); err != nil {
+		t.Errorf("PurgeSecret() error %v", err)
+	}
+	if versions, _ := ListSecretVersions(ctx, secstore, "id1"); len(versions) != 0 {
+		t.Errorf("ListSecretVersions() = %v after purge; want none", versions)
+	}
+	//listing keeps the expired entries, only PurgeExpiredTrash removes them
+	AddSecret(ctx, secstore, testSecret, "id2")
+	DeleteSecret(ctx, secstore, "id2")
+	t.Setenv("TRASHRETENTION", "1ns")
+	for i := 0; i < 2; i++ {
+		if entries, err := ListTrash(ctx, secstore); err != nil || len(entries) != 1 || !entries[0].Expired {
+			t.Errorf("ListTrash() = %v, %v; want id2 expired", entries, err)
+		}
+	}
+	if err := UndeleteSecret(ctx, secstore, "id2"); err == nil {
+		t.Errorf("UndeleteSecret() of an expired entry = nil; want an error")
+	}
+	if purged, err := PurgeExpiredTrash(ctx, secstore); err != nil || len(purged) != 1 || purged[0] != "id2" {
+		t.Errorf("PurgeExpiredTrash() = %v, %v; want id2", purged, err)
+	}
+	if entries, _ := ListTrash(ctx, secstore); len(entries) != 0 {
+		t.Errorf("ListTrash() = %v after PurgeExpiredTrash; want none", entries)
+	}
+}
+
+// test the history functions
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	AddSecret(ctx, secstore, testSecret, "id1")
+	updated := testSecret
+	updated.Credential = "password2"
+	AddSecret(ctx, secstore, updated, "id1")
+	changes, err := DiffSecretVersions(ctx, secstore, "id1", 1, 2, false)
+	if err != nil || len(changes) != 1 || changes[0].Field != "Credential" {
+		t.Errorf("DiffSecretVersions() = %v, %v; want Credential change", changes, err)
+	}
+	if err := RestoreSecretVersion(ctx, secstore, "id1", 1); err != nil {
+		t.Fatalf("RestoreSecretVersion() error %v", err)
+	}
+	if s, _ := GetSecret(ctx, secstore, "id1"); s.Credential != "password" {
+		t.Errorf("GetSecret() after restore = %v; want Credential password", s)
+	}
+	if versions, _ := ListSecretVersions(ctx, secstore, "id1"); len(versions) != 3 {
+		t.Errorf("ListSecretVersions() = %v; want 3 versions", versions)
+	}
+}
+
+// test the migration from and to the legacy layout
+func TestMigration(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	legacy := map[string]interface{}{
+		"id1": secret.ConvertFromSecret(testSecret),
+		"id2": map[string]interface{}{"username": "user2", "credential": "pwd2", "url": "", "comment": "", "lastupdate": "2020-01-01T00:00:00Z", "lastupdateby": "user"},
+	}
+	secstore.Backend.Put(ctx, "myapp", legacy, 0)
+	if count, err := MigrateToSplit(ctx, secstore); err != nil || count != 2 {
+		t.Fatalf("MigrateToSplit() = %d, %v; want 2", count, err)
+	}
+	if s, _ := GetSecret(ctx, secstore, "id2"); s.Username != "user2" {
+		t.Errorf("GetSecret() = %v; want Username user2", s)
+	}
+	if count, err := RollbackToBlob(ctx, secstore); err != nil || count != 2 {
+		t.Fatalf("RollbackToBlob() = %d, %v; want 2", count, err)
+	}
+	if CheckSecretID(ctx, secstore, "id1") {
+		t.Errorf("CheckSecretID() = true after rollback; want false")
+	}
+	if data, _, err := secstore.Backend.Get(ctx, "myapp", 0); err != nil || len(data) != 2 {
+		t.Errorf("legacy entry = %v, %v; want 2 secrets", data, err)
+	}
+}
+
+// test the wrap and unwrap of a list of secrets
+func TestWrapSecretList(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	AddSecret(ctx, secstore, testSecret, "id1")
+	token, err := WrapSecretList(ctx, secstore, []string{"id1", "unknown"}, "store", time.Minute)
+	if err != nil {
+		t.Fatalf("WrapSecretList() error %v", err)
+	}
+	data, err := UnWrappeSecret(ctx, secstore, token)
+	if err != nil || len(data) != 1 || !data["id1"].Equal(testSecret) {
+		t.Errorf("UnWrappeSecret() = %v, %v; want id1", data, err)
+	}
+	if _, err := secstore.Backend.Unwrap(ctx, token); err == nil {
+		t.Errorf("Unwrap() twice = nil error; want error")
+	}
+}
+
+// test the file backend keep the secrets encrypted between two opening
+func TestFileBackend(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "myvault.store")
+	backend, err := NewFileBackend(path, "passphrase")
+	if err != nil {
+		t.Fatalf("NewFileBackend() error %v", err)
+	}
+	AddSecret(ctx, SecretStore{Appname: "myapp", Backend: backend}, testSecret, "id1")
+	reopened, err := NewFileBackend(path, "passphrase")
+	if err != nil {
+		t.Fatalf("NewFileBackend() reopen error %v", err)
+	}
+	if s, _ := GetSecret(ctx, SecretStore{Appname: "myapp", Backend: reopened}, "id1"); !s.Equal(testSecret) {
+		t.Errorf("GetSecret() = %v; want %v", s, testSecret)
+	}
+	if _, err := NewFileBackend(path, "wrong"); err == nil {
+		t.Errorf("NewFileBackend() with wrong passphrase = nil error; want error")
+	}
+}
+
+// test the detection of the KV version of a mount
+func TestDetectKVVersion(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		response string
+		expected int
+	}{
+		{"kv2", `{"data":{"type":"kv","options":{"version":"2"}}}`, 2},
+		{"kv1", `{"data":{"type":"kv","options":{"version":"1"}}}`, 1},
+		{"kv without version", `{"data":{"type":"kv","options":null}}`, 1},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/sys/mounts/kv" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tc.response))
+			}))
+			defer server.Close()
+			client, _ := vault.New(vault.WithAddress(server.URL))
+			if version, err := detectKVVersion(context.Background(), client, "kv"); err != nil || version != tc.expected {
+				t.Errorf("detectKVVersion() = %d, %v; want %d", version, err, tc.expected)
+			}
+		})
+	}
+}
+
+// test the offline cache locked with a passphrase and with a key
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("ATTACHMENTMAXSIZE", "1048576")
+	secstore := newTestStore()
+	AddSecret(ctx, secstore, testSecret, "id1")
+	large := []byte(strings.Repeat("0123456789abcdef", AttachmentChunkSize/8))
+	if err := AttachFile(ctx, secstore, "id1", "keystore.p12", large); err != nil {
+		t.Fatalf("AttachFile() error %v", err)
+	}
+	cached, _ := GetSecret(ctx, secstore, "id1")
+	key, err := crypto.NewSoftwareKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var testcases = []struct {
+		name string
+		save CacheLock
+		open CacheLock
+		mode string
+	}{
+		{"passphrase", CacheLock{Passphrase: "passphrase"}, CacheLock{Passphrase: "passphrase"}, "passphrase"},
+		{"key", CacheLock{PublicKey: &key.PublicKey}, CacheLock{PrivateKey: key}, "yubikey"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "myvault.cache")
+			if err := SaveCache(ctx, secstore, path, tc.save); err != nil {
+				t.Fatalf("SaveCache() error %v", err)
+			}
+			if mode, _ := CacheLockMode(path); mode != tc.mode {
+				t.Errorf("CacheLockMode() = %s; want %s", mode, tc.mode)
+			}
+			offline, err := OpenCache(path, tc.open)
+			if err != nil {
+				t.Fatalf("OpenCache() error %v", err)
+			}
+			if s, _ := GetSecret(ctx, offline, "id1"); !s.Equal(cached) {
+				t.Errorf("GetSecret() = %v; want %v", s, cached)
+			}
+			//the chunked attachment can be extracted offline
+			if data, err := ReadAttachment(ctx, offline, "id1", "keystore.p12"); err != nil || !bytes.Equal(data, large) {
+				t.Errorf("ReadAttachment() = %d bytes, %v; want %d bytes", len(data), err, len(large))
+			}
+			if err := UpdateSecret(ctx, offline, testSecret, "id1", 0); !errors.Is(err, ErrReadOnly) {
+				t.Errorf("UpdateSecret() = %v; want ErrReadOnly", err)
+			}
+			if OfflineBanner(offline) == "" {
+				t.Errorf("OfflineBanner() is empty")
+			}
+		})
+	}
+	path := filepath.Join(t.TempDir(), "myvault.cache")
+	SaveCache(ctx, secstore, path, CacheLock{Passphrase: "passphrase"})
+	if _, err := OpenCache(path, CacheLock{Passphrase: "wrong"}); err == nil {
+		t.Errorf("OpenCache() with wrong passphrase = nil error; want error")
+	}
+}
+
+// test the folders: subtree listing, tree view, move and wrap of a folder
+func TestFolders(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	for _, id := range []string{"team/db/admin", "team/db/ro", "team/web", "root"} {
+		if err := AddSecret(ctx, secstore, testSecret, id); err != nil {
+			t.Fatalf("AddSecret(%s) error %v", id, err)
+		}
+	}
+	if err := AddSecret(ctx, secstore, testSecret, "team/"); err == nil {
+		t.Errorf("AddSecret(team/) = nil error; want error")
+	}
+	if secrets, _ := getFolderSecrets(ctx, secstore, "team/db"); len(secrets) != 2 {
+		t.Errorf("getFolderSecrets(team/db) = %v; want 2 secrets", secrets)
+	}
+	lines := treeLines(buildTree([]string{"team/db/admin", "team/web"}, ""), "")
+	expected := []string{"└── team/", "    ├── db/", "    │   └── admin", "    └── web"}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("treeLines() = %q; want %q", lines, expected)
+	}
+	if count, err := MoveSecret(ctx, secstore, "team/db/", "prod/db/"); err != nil || count != 2 {
+		t.Fatalf("MoveSecret(folder) = %d, %v; want 2", count, err)
+	}
+	if !CheckSecretID(ctx, secstore, "prod/db/admin") || CheckSecretID(ctx, secstore, "team/db/admin") {
+		t.Errorf("team/db/admin not moved to prod/db/admin")
+	}
+	if _, err := MoveSecret(ctx, secstore, "root", "prod/db/admin"); err == nil {
+		t.Errorf("MoveSecret() over an existing secret = nil error; want error")
+	}
+	if count, err := MoveSecret(ctx, secstore, "root", "prod/"); err != nil || count != 1 || !CheckSecretID(ctx, secstore, "prod/root") {
+		t.Errorf("MoveSecret(root, prod/) = %d, %v; want prod/root", count, err)
+	}
+	token, err := WrapSecretList(ctx, secstore, []string{"prod/db/"}, "store", time.Minute)
+	if err != nil {
+		t.Fatalf("WrapSecretList() error %v", err)
+	}
+	if data, _ := UnWrappeSecret(ctx, secstore, token); len(data) != 2 {
+		t.Errorf("UnWrappeSecret() = %v; want the 2 secrets of prod/db/", data)
+	}
+}
+
+// test the namespace header is sent by the client and changed by SwitchNamespace
+func TestNamespace(t *testing.T) {
+	var namespaces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespaces = append(namespaces, r.Header.Get("X-Vault-Namespace"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"type":"kv","options":{"version":"2"}}}`))
+	}))
+	defer server.Close()
+	t.Setenv("VAULTURL", server.URL)
+	t.Setenv("APPNAME", "myapp")
+	t.Setenv("MOUNTPATH", "kv")
+	//the transit backend sends no request until a secret is read or written
+	t.Setenv("TRANSITKEY", "myapp")
+	t.Setenv("TRANSITMOUNT", "transit")
+	ctx := context.Background()
+	secstore, err := ConnectVaultWithTokenNamespace(ctx, "token", "team1")
+	if err != nil || secstore.Namespace != "team1" {
+		t.Fatalf("ConnectVaultWithTokenNamespace() = %v, %v; want namespace team1", secstore.Namespace, err)
+	}
+	switched, err := SwitchNamespace(ctx, secstore, "team2/")
+	if err != nil || switched.Namespace != "team2" {
+		t.Fatalf("SwitchNamespace() = %v, %v; want namespace team2", switched.Namespace, err)
+	}
+	if len(namespaces) != 2 || namespaces[0] != "team1" || namespaces[1] != "team2" {
+		t.Errorf("X-Vault-Namespace headers = %v; want [team1 team2]", namespaces)
+	}
+	//the switched store would write the secrets without the zero knowledge encryption
+	sealed := SealedStore(secstore, make([]byte, 32))
+	if switched, err := SwitchNamespace(ctx, sealed, "team2"); err == nil || switched.Namespace != "team1" {
+		t.Errorf("SwitchNamespace() with zero knowledge = %v, %v; want an error", switched.Namespace, err)
+	}
+}
+
+// test the compare and the one way and two way sync of two stores
+func TestSyncStores(t *testing.T) {
+	ctx := context.Background()
+	source, target := newTestStore(), newTestStore()
+	newer := testSecret
+	newer.Credential = "newer"
+	newer.LastUpdate = testSecret.LastUpdate.Add(time.Hour)
+	AddSecret(ctx, source, testSecret, "both")
+	AddSecret(ctx, target, testSecret, "both")
+	AddSecret(ctx, source, testSecret, "source-only")
+	AddSecret(ctx, target, testSecret, "target-only")
+	AddSecret(ctx, source, testSecret, "changed")
+	AddSecret(ctx, target, newer, "changed")
+
+	changes, err := SyncStores(ctx, source, target, SyncOptions{DryRun: true})
+	expected := []SyncChange{{"changed", SyncChanged, SyncToTarget}, {"source-only", SyncAdded, SyncToTarget}, {"target-only", SyncRemoved, SyncSkip}}
+	if err != nil || len(changes) != len(expected) {
+		t.Fatalf("SyncStores() dry run = %v, %v; want %v", changes, err, expected)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("SyncStores() dry run change %d = %v; want %v", i, changes[i], expected[i])
+		}
+	}
+	if CheckSecretID(ctx, target, "source-only") {
+		t.Errorf("dry run wrote source-only to the target")
+	}
+
+	if _, err := SyncStores(ctx, source, target, SyncOptions{TwoWay: true}); err != nil {
+		t.Fatalf("SyncStores() two way error %v", err)
+	}
+	if s, _ := GetSecret(ctx, source, "changed"); s.Credential != "newer" {
+		t.Errorf("two way sync kept %v in the source; want the newer target secret", s)
+	}
+	if !CheckSecretID(ctx, source, "target-only") || !CheckSecretID(ctx, target, "source-only") {
+		t.Errorf("two way sync did not copy the missing secrets")
+	}
+	if changes, _ := CompareStores(ctx, source, target); len(changes) != 0 {
+		t.Errorf("CompareStores() after sync = %v; want none", changes)
+	}
+
+	DeleteSecret(ctx, source, "target-only")
+	if _, err := SyncStores(ctx, source, target, SyncOptions{Prune: true}); err != nil || CheckSecretID(ctx, target, "target-only") {
+		t.Errorf("one way sync with prune = %v; want target-only deleted", err)
+	}
+}
+
+// test the search ranking and that the credential is not searched
+func TestSearchSecrets(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	db := testSecret
+	db.URL = "https://db.example.com"
+	AddSecret(ctx, secstore, db, "prod/database")
+	AddSecret(ctx, secstore, testSecret, "db")
+	AddSecret(ctx, secstore, testSecret, "dashboard")
+	results, err := SearchSecrets(ctx, secstore, "db")
+	if err != nil {
+		t.Fatalf("SearchSecrets() error %v", err)
+	}
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.SecretID)
+	}
+	expected := []string{"db", "prod/database", "dashboard"}
+	if strings.Join(ids, ",") != strings.Join(expected, ",") {
+		t.Errorf("SearchSecrets(db) = %v; want %v", ids, expected)
+	}
+	if results, _ := SearchSecrets(ctx, secstore, "password"); len(results) != 0 {
+		t.Errorf("SearchSecrets(password) = %v; want no match on the credential", results)
+	}
+	if results, _ := SearchSecrets(ctx, secstore, "prod example"); len(results) != 1 {
+		t.Errorf("SearchSecrets(prod example) = %v; want prod/database", results)
+	}
+}
+
+// test the filter and the wrap of the secrets carrying a tag
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	tagged := testSecret
+	tagged.Tags = []string{"db", "prod"}
+	AddSecret(ctx, secstore, tagged, "id1")
+	AddSecret(ctx, secstore, testSecret, "id2")
+	if secrets, _ := getTaggedSecrets(ctx, secstore, "prod"); len(secrets) != 1 || !secrets["id1"].Equal(tagged) {
+		t.Errorf("getTaggedSecrets(prod) = %v; want id1", secrets)
+	}
+	if tags, _ := ListTags(ctx, secstore); len(tags) != 2 || tags["db"] != 1 {
+		t.Errorf("ListTags() = %v; want db and prod", tags)
+	}
+	token, err := WrapSecretTag(ctx, secstore, "db", "store", time.Minute)
+	if err != nil {
+		t.Fatalf("WrapSecretTag() error %v", err)
+	}
+	if data, _ := UnWrappeSecret(ctx, secstore, token); len(data) != 1 || !data["id1"].Equal(tagged) {
+		t.Errorf("UnWrappeSecret() = %v; want id1", data)
+	}
+	if _, err := WrapSecretTag(ctx, secstore, "unknown", "store", time.Minute); err == nil {
+		t.Errorf("WrapSecretTag(unknown) = nil error; want error")
+	}
+}
+
+// test a typed secret is stored, searched and wrapped with its fields
+func TestTypedSecret(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	apikey := secret.Secret{Type: "apikey", URL: "https://api.example.com", Fields: map[string]string{"APIKey": "k-123", "Scopes": "read write"}}
+	if err := AddSecret(ctx, secstore, apikey, "ci/token"); err != nil {
+		t.Fatalf("AddSecret() error %v", err)
+	}
+	if got, _, _ := readSecret(ctx, secstore, "ci/token"); !got.Equal(apikey) || got.MainSecret() != "k-123" {
+		t.Errorf("readSecret() = %v; want %v", got, apikey)
+	}
+	if results, _ := SearchSecrets(ctx, secstore, "write"); len(results) != 1 || results[0].Field != "Fields" {
+		t.Errorf("SearchSecrets(write) = %v; want ci/token on Fields", results)
+	}
+	if results, _ := SearchSecrets(ctx, secstore, "k-123"); len(results) != 0 {
+		t.Errorf("SearchSecrets() found the api key %v", results)
+	}
+	token, err := WrapSecretList(ctx, secstore, []string{"ci/"}, "store", time.Minute)
+	if err != nil {
+		t.Fatalf("WrapSecretList() error %v", err)
+	}
+	if data, _ := UnWrappeSecret(ctx, secstore, token); !data["ci/token"].Equal(apikey) {
+		t.Errorf("UnWrappeSecret() = %v; want %v", data, apikey)
+	}
+}
+
+// test a small (inline) and a large (chunked) attachment
+func TestAttachments(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("ATTACHMENTMAXSIZE", "1048576")
+	secstore := newTestStore()
+	AddSecret(ctx, secstore, testSecret, "id1")
+	small := []byte("apiVersion: v1\nkind: Config\n")
+	if err := AttachFile(ctx, secstore, "id1", "license", make([]byte, 1048577)); err == nil {
+		t.Errorf("AttachFile() over the size limit = nil error; want error")
+	}
+	large := []byte(strings.Repeat("0123456789abcdef", AttachmentChunkSize/8))
+	if err := AttachFile(ctx, secstore, "id1", "kubeconfig", small); err != nil {
+		t.Fatalf("AttachFile(small) error %v", err)
+	}
+	if err := AttachFile(ctx, secstore, "id1", "keystore.p12", large); err != nil {
+		t.Fatalf("AttachFile(large) error %v", err)
+	}
+	if err := AttachFile(ctx, secstore, "id1", "kubeconfig", small); err == nil {
+		t.Errorf("AttachFile() of an existing name = nil error; want error")
+	}
+	attachments, _ := ListAttachments(ctx, secstore, "id1")
+	if len(attachments) != 2 || attachments[0].Data == "" || attachments[1].Chunks != 2 {
+		t.Fatalf("ListAttachments() = %v; want kubeconfig inline and keystore.p12 in 2 chunks", attachments)
+	}
+	if ids, _ := listSecretIDs(ctx, secstore, ""); len(ids) != 1 {
+		t.Errorf("listSecretIDs() = %v; want the chunks apart from the secrets", ids)
+	}
+	path := filepath.Join(t.TempDir(), "keystore.p12")
+	if err := ExtractAttachment(ctx, secstore, "id1", "keystore.p12", path); err != nil {
+		t.Fatalf("ExtractAttachment() error %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(large) {
+		t.Errorf("ExtractAttachment() wrote %d bytes; want %d", len(data), len(large))
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("ExtractAttachment() mode = %v; want 0600", info.Mode().Perm())
+	}
+	if err := DeleteAttachment(ctx, secstore, "id1", "keystore.p12"); err != nil {
+		t.Fatalf("DeleteAttachment() error %v", err)
+	}
+	if _, err := ReadAttachment(ctx, secstore, "id1", "keystore.p12"); err == nil {
+		t.Errorf("ReadAttachment() of a deleted attachment = nil error; want error")
+	}
+	if data, err := ReadAttachment(ctx, secstore, "id1", "kubeconfig"); err != nil || string(data) != string(small) {
+		t.Errorf("ReadAttachment(kubeconfig) = %q, %v; want %q", data, err, small)
+	}
+}
+
+// a backend without versions like KV v1, a delete is permanent
+type unversionedBackend struct {
+	Backend
+}
+
+func (b unversionedBackend) Versioned() bool {
+	return false
+}
+
+func (b unversionedBackend) Get(ctx context.Context, path string, version int64) (map[string]interface{}, int64, error) {
+	data, _, err := b.Backend.Get(ctx, path, version)
+	return data, 0, err
+}
+
+// the entry is always overwritten
+func (b unversionedBackend) Put(ctx context.Context, path string, data map[string]interface{}, cas int64) (int64, error) {
+	versions, _ := b.Backend.Versions(ctx, path)
+	_, err := b.Backend.Put(ctx, path, data, int64(len(versions)))
+	return 0, err
+}
+
+func (b unversionedBackend) Delete(ctx context.Context, path string, version int64) error {
+	return b.Backend.Destroy(ctx, path)
+}
+
+func (b unversionedBackend) Versions(ctx context.Context, path string) ([]SecretVersion, error) {
+	return nil, ErrNotSupported
+}
+
+// test the chunks of the attachments are destroyed with the last secret referencing them
+func TestPurgeAttachments(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("ATTACHMENTMAXSIZE", "1048576")
+	t.Setenv("TRASHRETENTION", "1h")
+	large := []byte(strings.Repeat("0123456789abcdef", AttachmentChunkSize/8))
+	chunkFound := func(secstore SecretStore, secretID string) func() bool {
+		attachments, _ := ListAttachments(ctx, secstore, secretID)
+		if len(attachments) != 1 || attachments[0].Chunks != 2 {
+			t.Fatalf("ListAttachments() = %v; want 1 attachment in 2 chunks", attachments)
+		}
+		return func() bool {
+			_, _, err := secstore.Backend.Get(ctx, attachmentChunkPath(secstore, attachments[0].ChunkID, 0), 0)
+			return err == nil
+		}
+	}
+	secstore := newTestStore()
+	AddSecret(ctx, secstore, testSecret, "id1")
+	AttachFile(ctx, secstore, "id1", "keystore.p12", large)
+	found := chunkFound(secstore, "id1")
+	//a newer version without the attachment, the purge must still find it in the first version
+	updated := testSecret
+	updated.Credential = "password2"
+	UpdateSecret(ctx, secstore, updated, "id1", 2)
+	AddSecret(ctx, secstore, testSecret, "id2")
+	AttachFile(ctx, secstore, "id2", "keystore.p12", large)
+	shared := chunkFound(secstore, "id2")
+	//the moved secret keep the chunks of the attachment
+	if _, err := MoveSecret(ctx, secstore, "id2", "id3"); err != nil {
+		t.Fatalf("MoveSecret() error %v", err)
+	}
+	DeleteSecret(ctx, secstore, "id1")
+	if err := PurgeSecret(ctx, secstore, "id1"); err != nil || found() {
+		t.Errorf("PurgeSecret() = %v, chunk found %v; want the chunks destroyed", err, found())
+	}
+	if err := PurgeSecret(ctx, secstore, "id2"); err != nil || !shared() {
+		t.Errorf("PurgeSecret() = %v, chunk found %v; want the chunks of id3 kept", err, shared())
+	}
+	DeleteSecret(ctx, secstore, "id3")
+	if err := PurgeSecret(ctx, secstore, "id3"); err != nil || shared() {
+		t.Errorf("PurgeSecret() = %v, chunk found %v; want the chunks destroyed", err, shared())
+	}
+	//without versions the delete is permanent
+	secstore.Backend = unversionedBackend{NewMemoryBackend()}
+	AddSecret(ctx, secstore, testSecret, "id1")
+	AttachFile(ctx, secstore, "id1", "keystore.p12", large)
+	found = chunkFound(secstore, "id1")
+	if err := DeleteSecret(ctx, secstore, "id1"); err != nil || found() {
+		t.Errorf("DeleteSecret() = %v, chunk found %v; want the chunks destroyed", err, found())
+	}
+}
+
+// test the rotation report with secret, tag and default max ages
+func TestRotationReport(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	now := time.Date(2021, 01, 01, 00, 00, 00, 00, time.UTC)
+	fresh := testSecret
+	fresh.LastUpdate = now.Add(-24 * time.Hour)
+	own := testSecret
+	own.MaxAge = "30d"
+	tagged := fresh
+	tagged.Tags = []string{"db", "prod"}
+	AddSecret(ctx, secstore, testSecret, "old")
+	AddSecret(ctx, secstore, own, "own")
+	AddSecret(ctx, secstore, fresh, "fresh")
+	AddSecret(ctx, secstore, tagged, "tagged")
+	policy := RotationPolicy{Default: "370d", Tags: map[string]string{"prod": "10d", "db": "7d"}, Warning: "14d"}
+	entries, err := RotationReport(ctx, secstore, policy, now)
+	if err != nil {
+		t.Fatalf("RotationReport() error %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("RotationReport() = %v; want own, old and tagged", entries)
+	}
+	if entries[0].SecretID != "own" || entries[0].Policy != "secret" || entries[0].Status != RotationOverdue {
+		t.Errorf("RotationReport()[0] = %v; want own overdue by its own max age", entries[0])
+	}
+	if entries[1].SecretID != "old" || entries[1].Policy != "default" || entries[1].Status != RotationDueSoon {
+		t.Errorf("RotationReport()[1] = %v; want old due soon by default", entries[1])
+	}
+	if entries[2].SecretID != "tagged" || entries[2].Policy != "tag:db" || entries[2].Status != RotationDueSoon {
+		t.Errorf("RotationReport()[2] = %v; want tagged due soon by tag db", entries[2])
+	}
+	if report, err := RotationReportJSON(entries[:1]); err != nil || !strings.Contains(report, `"status": "overdue"`) {
+		t.Errorf("RotationReportJSON() = %s, %v", report, err)
+	}
+	//a secret that can not be read is reported first
+	secstore.Backend.Put(ctx, secretPath(secstore, "broken"), map[string]interface{}{"Username": 1}, 0)
+	if entries, err := RotationReport(ctx, secstore, policy, now); err != nil || len(entries) != 4 || entries[0].SecretID != "broken" || entries[0].Status != RotationUnreadable {
+		t.Errorf("RotationReport() = %v, %v; want broken unreadable first", entries, err)
+	}
+}
+
+// test the audit of the credentials against a breach list
+func TestAuditBreached(t *testing.T) {
+	ctx := context.Background()
+	secstore := newTestStore()
+	weak := testSecret
+	weak.Credential = "123456"
+	AddSecret(ctx, secstore, testSecret, "id1")
+	AddSecret(ctx, secstore, weak, "id2")
+	//SHA-1 of "123456" and "password" ordered by hash
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	os.WriteFile(path, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n7C4A8D09CA3762AF61E59520943DC26494F8941B:37359195\n"), 0600)
+	list, err := crypto.OpenBreachList(path)
+	if err != nil {
+		t.Fatalf("OpenBreachList() error %v", err)
+	}
+	defer list.Close()
+	entries, err := AuditBreached(ctx, secstore, list)
+	if err != nil || len(entries) != 2 || entries[0].SecretID != "id1" || entries[1].Count != 37359195 {
+		t.Errorf("AuditBreached() = %v, %v; want id1 and id2", entries, err)
+	}
+}
+
+// test the issues found by the health report
+func TestHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("PASSWORDMINSCORE", "0")
+	secstore := newTestStore()
+	strong := secret.Secret{Username: "svc", Credential: "x7#Kp9!qLm2$vB", URL: "https://db", LastUpdate: time.Date(2020, 12, 01, 00, 00, 00, 00, time.UTC)}
+	copied := strong
+	copied.Username = "other"
+	incomplete := secret.Secret{Credential: "Zq8!rT2#mW9$kP", LastUpdate: strong.LastUpdate}
+	AddSecret(ctx, secstore, strong, "a")
+	AddSecret(ctx, secstore, copied, "b")
+	AddSecret(ctx, secstore, strong, "c")
+	AddSecret(ctx, secstore, testSecret, "d")
+	AddSecret(ctx, secstore, incomplete, "e")
+	secstore.Backend.Put(ctx, secretPath(secstore, "f"), map[string]interface{}{"value": "not a secret"}, 0)
+	report, err := HealthCheck(ctx, secstore, time.Date(2021, 01, 01, 00, 00, 00, 00, time.UTC))
+	if err != nil {
+		t.Fatalf("HealthCheck() error %v", err)
+	}
+	issues := make(map[string][]string)
+	for _, issue := range report.Issues {
+		issues[issue.SecretID] = append(issues[issue.SecretID], issue.Kind)
+	}
+	want := map[string]string{
+		"a": "reused,duplicate",
+		"b": "reused",
+		"c": "reused,duplicate",
+		"d": "weak,never updated",
+		"e": "empty username,empty url",
+		"f": "invalid",
+	}
+	for id, kinds := range want {
+		if got := strings.Join(issues[id], ","); got != kinds {
+			t.Errorf("HealthCheck() issues of %s = %s; want %s", id, got, kinds)
+		}
+	}
+	if report.Secrets != 6 || report.Score != 0 || report.Counts[HealthReused] != 3 {
+		t.Errorf("HealthCheck() = %d secrets, score %d, counts %v; want 6 secrets, score 0", report.Secrets, report.Score, report.Counts)
+	}
+	if output, err := HealthReportJSON(report); err != nil || !strings.Contains(output, `"score": 0`) {
+		t.Errorf("HealthReportJSON() = %s, %v", output, err)
+	}
+}
+
+// test the generation with a vault password policy and the fallback to the local generator
+func TestGeneratePassword(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/sys/policies/password/corp/generate":
+			w.Write([]byte(`{"data":{"password":"Vault-Generated-1"}}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULTPASSWORDPOLICY", "corp")
+	t.Setenv("PASSWORDPOLICY", "pin")
+	t.Setenv("PASSWORDPOLICIES", `{"short": {"pattern": "cvcv"}}`)
+	ctx := context.Background()
+	client, _ := vault.New(vault.WithAddress(server.URL))
+	vaultStore := newTestStore()
+	vaultStore.Client = client
+	var testcases = []struct {
+		name     string
+		secstore SecretStore
+		policy   string
+		expected string
+	}{
+		{"vault default", vaultStore, "", `^Vault-Generated-1$`},
+		{"vault named", vaultStore, "vault:corp", `^Vault-Generated-1$`},
+		{"vault not permitted", vaultStore, "vault:denied", `^[0-9]{6}$`},
+		{"local store", newTestStore(), "", `^[0-9]{6}$`},
+		{"local policy", vaultStore, "short", `^[bcdfghjklmnpqrstvwxz][aeiouy][bcdfghjklmnpqrstvwxz][aeiouy]$`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			password, err := GeneratePassword(ctx, tc.secstore, tc.policy)
+			if err != nil || !regexp.MustCompile(tc.expected).MatchString(password) {
+				t.Errorf("GeneratePassword(%s) = %s, %v; want %s", tc.policy, password, err, tc.expected)
+			}
+		})
+	}
+	if _, err := GeneratePassword(ctx, vaultStore, "unknown"); err == nil {
+		t.Errorf("GeneratePassword(unknown) want an error")
+	}
+}
+
+// test the transit encryption of the sensitive fields, the migration of the plaintext entries and the rewrap
+func TestTransit(t *testing.T) {
+	//a fake transit engine, the ciphertext is the key version and the base64 plaintext
+	keyVersion := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]string
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		ciphertext := func(b64 string) string { return fmt.Sprintf("vault:v%d:%s", keyVersion, b64) }
+		plaintext := func(c string) string { return c[strings.LastIndex(c, ":")+1:] }
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/myapp":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": ciphertext(request["plaintext"])}})
+		case "/v1/transit/decrypt/myapp":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": plaintext(request["ciphertext"])}})
+		case "/v1/transit/rewrap/myapp":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": ciphertext(plaintext(request["ciphertext"]))}})
+		case "/v1/transit/keys/myapp/rotate":
+			keyVersion++
+			w.Write([]byte(`{"data":{}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("ATTACHMENTMAXSIZE", "1048576")
+	t.Setenv("TRASHRETENTION", "1h")
+	ctx := context.Background()
+	client, _ := vault.New(vault.WithAddress(server.URL))
+	plain := newTestStore()
+	secstore := plain
+	secstore.Backend = &TransitBackend{Backend: plain.Backend, Client: client, Mountpath: "transit", Key: "myapp"}
+	if _, err := TransitRewrap(ctx, plain); !errors.Is(err, ErrTransitDisabled) {
+		t.Errorf("TransitRewrap() without transit = %v; want ErrTransitDisabled", err)
+	}
+	raw := func(path string) map[string]interface{} {
+		data, _, _ := plain.Backend.Get(ctx, plain.Appname+"/"+path, 0)
+		return data
+	}
+	AddSecret(ctx, plain, testSecret, "old")
+	//a credential written before transit that looks like a ciphertext
+	legacy := testSecret
+	legacy.Credential = "vault:v1:legacy"
+	AddSecret(ctx, plain, legacy, "legacy")
+	//a plaintext secret in the trash
+	AddSecret(ctx, plain, testSecret, "trashed")
+	DeleteSecret(ctx, plain, "trashed")
+	AddSecret(ctx, secstore, testSecret, "new")
+	AttachFile(ctx, secstore, "new", "keystore.p12", []byte(strings.Repeat("0123456789abcdef", AttachmentChunkSize/8)))
+	if data := raw("new"); !strings.HasPrefix(data["Credential"].(string), "vault:v1:") || data["Username"] != "user" || !strings.HasPrefix(data["Attachments"].(string), "vault:v1:") {
+		t.Errorf("stored entry = %v; want the Credential and the Attachments encrypted", data)
+	}
+	attachments, _ := ListAttachments(ctx, secstore, "new")
+	if len(attachments) != 1 || attachments[0].Chunks != 2 {
+		t.Fatalf("ListAttachments() = %v; want 1 attachment in 2 chunks", attachments)
+	}
+	chunk, _, _ := plain.Backend.Get(ctx, attachmentChunkPath(plain, attachments[0].ChunkID, 0), 0)
+	if !strings.HasPrefix(chunk["Data"].(string), "vault:v1:") {
+		t.Errorf("stored chunk is not encrypted")
+	}
+	for _, id := range []string{"old", "new"} {
+		if s, err := GetSecret(ctx, secstore, id); err != nil || s.Credential != testSecret.Credential {
+			t.Errorf("GetSecret(%s) = %v, %v; want the decrypted secret", id, s, err)
+		}
+	}
+	if s, err := GetSecret(ctx, secstore, "legacy"); err != nil || s.Credential != legacy.Credential {
+		t.Errorf("GetSecret(legacy) = %v, %v; want the plaintext credential", s, err)
+	}
+	if data, err := ReadAttachment(ctx, secstore, "new", "keystore.p12"); err != nil || len(data) != AttachmentChunkSize*2 {
+		t.Errorf("ReadAttachment() = %d bytes, %v; want the decrypted attachment", len(data), err)
+	}
+	//the plaintext entries written before transit are encrypted
+	if count, err := TransitEncryptExisting(ctx, secstore); err != nil || count != 3 {
+		t.Errorf("TransitEncryptExisting() = %d, %v; want 3 entries", count, err)
+	}
+	//the secret in the trash is encrypted and stays in the trash
+	if trash, err := ListTrash(ctx, plain); err != nil || len(trash) != 1 || trash[0].SecretID != "trashed" {
+		t.Errorf("ListTrash() = %v, %v; want the trashed secret", trash, err)
+	}
+	if versions, _ := ListSecretVersions(ctx, plain, "trashed"); len(versions) != 2 || !versions[0].Destroyed || versions[1].DeletionTime == "" {
+		t.Errorf("ListSecretVersions() = %v; want the plaintext version destroyed and the encrypted one deleted", versions)
+	}
+	UndeleteSecret(ctx, plain, "trashed")
+	if data := raw("trashed"); !strings.HasPrefix(data["Credential"].(string), "vault:v1:") {
+		t.Errorf("stored entry = %v; want the Credential of the trashed secret encrypted", data)
+	}
+	DeleteSecret(ctx, plain, "trashed")
+	if s, err := GetSecret(ctx, secstore, "legacy"); err != nil || s.Credential != legacy.Credential || raw("legacy")["Credential"] == legacy.Credential {
+		t.Errorf("GetSecret(legacy) = %v, %v; want the credential encrypted", s, err)
+	}
+	if data := raw("old"); !strings.HasPrefix(data["Credential"].(string), "vault:v1:") {
+		t.Errorf("stored entry = %v; want the Credential encrypted", data)
+	}
+	//the plaintext version is destroyed
+	if versions, _ := ListSecretVersions(ctx, plain, "old"); len(versions) != 2 || !versions[0].Destroyed || versions[1].Destroyed {
+		t.Errorf("ListSecretVersions() = %v; want the plaintext version destroyed", versions)
+	}
+	//the 4 secrets and the 2 chunks are rewrapped with the new key version
+	if count, err := TransitRotate(ctx, secstore); err != nil || count != 6 {
+		t.Errorf("TransitRotate() = %d, %v; want 6 entries", count, err)
+	}
+	if data := raw("old"); !strings.HasPrefix(data["Credential"].(string), "vault:v2:") {
+		t.Errorf("stored entry = %v; want the Credential rewrapped", data)
+	}
+	//the rotation keeps the history, the old key versions are retired by the operator
+	if versions, _ := ListSecretVersions(ctx, plain, "old"); len(versions) != 3 || versions[1].Destroyed || versions[2].Destroyed {
+		t.Errorf("ListSecretVersions() = %v; want the version of the old key kept", versions)
+	}
+	if count, err := TransitRewrap(ctx, secstore); err != nil || count != 0 {
+		t.Errorf("TransitRewrap() = %d, %v; want no entry to rewrap", count, err)
+	}
+	if s, err := GetSecret(ctx, secstore, "old"); err != nil || s.Credential != testSecret.Credential {
+		t.Errorf("GetSecret() = %v, %v; want the decrypted secret", s, err)
+	}
+}
+
+// test the zero knowledge keyring with software keys and the recovery code
+func TestZeroKnowledge(t *testing.T) {
+	ctx := context.Background()
+	pinFile := filepath.Join(t.TempDir(), "myvault.keyring")
+	t.Setenv("KEYRINGPINFILE", pinFile)
+	plain := newTestStore()
+	yubikey, _ := crypto.NewSoftwareKey()
+	if _, err := UnlockKeyring(ctx, plain, "alice", yubikey); !errors.Is(err, ErrKeyringNotFound) {
+		t.Errorf("UnlockKeyring() = %v; want ErrKeyringNotFound", err)
+	}
+	dataKey, code, err := InitKeyring(ctx, plain, "alice", &yubikey.PublicKey)
+	if err != nil || len(dataKey) != 32 || code == "" {
+		t.Fatalf("InitKeyring() = %d bytes, %s, %v; want a data key and a recovery code", len(dataKey), code, err)
+	}
+	if _, _, err := InitKeyring(ctx, plain, "alice", &yubikey.PublicKey); err == nil {
+		t.Errorf("InitKeyring() of an existing keyring = nil error; want error")
+	}
+	if key, err := UnlockKeyring(ctx, plain, "alice", yubikey); err != nil || !bytes.Equal(key, dataKey) {
+		t.Errorf("UnlockKeyring() = %v; want the data key", err)
+	}
+	if ids, _ := listSecretIDs(ctx, plain, ""); len(ids) != 0 {
+		t.Errorf("listSecretIDs() = %v; want the keyring apart from the secrets", ids)
+	}
+	//the sensitive fields are stored encrypted
+	AddSecret(ctx, plain, testSecret, "old")
+	//a credential written before ZEROKNOWLEDGE that looks like a sealed value
+	legacy := testSecret
+	legacy.Credential = sealedPrefix + "legacy"
+	AddSecret(ctx, plain, legacy, "legacy")
+	secstore := SealedStore(plain, dataKey)
+	if s, err := GetSecret(ctx, secstore, "legacy"); err != nil || s.Credential != legacy.Credential {
+		t.Errorf("GetSecret(legacy) = %v, %v; want the plaintext credential", s, err)
+	}
+	if _, err := SealExisting(ctx, plain); !errors.Is(err, ErrZeroKnowledgeDisabled) {
+		t.Errorf("SealExisting() without data key = %v; want ErrZeroKnowledgeDisabled", err)
+	}
+	//the tools can not use a store without the data key when ZEROKNOWLEDGE is set
+	t.Setenv("ZEROKNOWLEDGE", "true")
+	if err := CheckZeroKnowledge(plain); !errors.Is(err, ErrZeroKnowledgeDisabled) {
+		t.Errorf("CheckZeroKnowledge() without data key = %v; want ErrZeroKnowledgeDisabled", err)
+	}
+	if err := CheckZeroKnowledge(secstore); err != nil {
+		t.Errorf("CheckZeroKnowledge() = %v; want nil", err)
+	}
+	AddSecret(ctx, secstore, testSecret, "new")
+	//edited after ZEROKNOWLEDGE was enabled, its first version is plaintext
+	AddSecret(ctx, plain, testSecret, "edited")
+	AddSecret(ctx, secstore, testSecret, "edited")
+	raw := func(id string) map[string]interface{} {
+		data, _, _ := plain.Backend.Get(ctx, secretPath(plain, id), 0)
+		return data
+	}
+	if data := raw("new"); !strings.HasPrefix(data["Credential"].(string), sealedPrefix) || data["Username"] != "user" || data[sealedField] == nil {
+		t.Errorf("stored entry = %v; want the Credential encrypted and the entry marked", data)
+	}
+	if count, err := SealExisting(ctx, secstore); err != nil || count != 2 {
+		t.Errorf("SealExisting() = %d, %v; want 2 entries", count, err)
+	}
+	if s, err := GetSecret(ctx, secstore, "legacy"); err != nil || s.Credential != legacy.Credential || raw("legacy")["Credential"] == legacy.Credential {
+		t.Errorf("GetSecret(legacy) = %v, %v; want the credential encrypted", s, err)
+	}
+	if data := raw("old"); !strings.HasPrefix(data["Credential"].(string), sealedPrefix) {
+		t.Errorf("stored entry = %v; want the Credential encrypted", data)
+	}
+	//the plaintext versions are destroyed
+	for _, id := range []string{"old", "edited"} {
+		if versions, _ := ListSecretVersions(ctx, plain, id); len(versions) != 2 || !versions[0].Destroyed || versions[1].Destroyed {
+			t.Errorf("ListSecretVersions(%s) = %v; want the plaintext version destroyed", id, versions)
+		}
+	}
+	for _, id := range []string{"old", "new"} {
+		if s, err := GetSecret(ctx, secstore, id); err != nil || !s.Equal(testSecret) {
+			t.Errorf("GetSecret(%s) = %v, %v; want the decrypted secret", id, s, err)
+		}
+	}
+	//another key can not read the secrets
+	other, _ := crypto.NewSoftwareKey()
+	if _, err := UnlockKeyring(ctx, plain, "alice", other); err == nil {
+		t.Errorf("UnlockKeyring() with another key = nil error; want error")
+	}
+	otherKey, _ := crypto.NewDataKey()
+	if _, err := GetSecret(ctx, SealedStore(plain, otherKey), "new"); err == nil {
+		t.Errorf("GetSecret() with another data key = nil error; want error")
+	}
+	//the recovery code unlocks the keyring and wraps it to the new key
+	if _, err := RecoverKeyring(ctx, plain, "alice", "AAAA-AAAA-AAAA-AAAA-AAAA-AAAA-AAAA-AAAA", nil); err == nil {
+		t.Errorf("RecoverKeyring() with a wrong code = nil error; want error")
+	}
+	if key, err := RecoverKeyring(ctx, plain, "alice", strings.ToLower(code), &other.PublicKey); err != nil || !bytes.Equal(key, dataKey) {
+		t.Fatalf("RecoverKeyring() = %v; want the data key", err)
+	}
+	if key, err := UnlockKeyring(ctx, plain, "alice", other); err != nil || !bytes.Equal(key, dataKey) {
+		t.Errorf("UnlockKeyring() with the new key = %v; want the data key", err)
+	}
+	if _, err := UnlockKeyring(ctx, plain, "alice", yubikey); err == nil {
+		t.Errorf("UnlockKeyring() with the lost key = nil error; want error")
+	}
+	//no keyring is created while secrets are sealed
+	if _, _, err := InitKeyring(ctx, plain, "bob", &other.PublicKey); !errors.Is(err, ErrKeyringMissing) {
+		t.Errorf("InitKeyring() with sealed secrets = %v; want ErrKeyringMissing", err)
+	}
+	//a keyring replaced by an admin wraps another data key, it does not match the pinned fingerprint
+	keyring, version, _ := readKeyring(ctx, plain, "alice")
+	forged := keyring
+	forged.WrappedKey, _ = crypto.WrapKey(&other.PublicKey, otherKey)
+	writeKeyring(ctx, plain, "alice", forged, version)
+	if _, err := UnlockKeyring(ctx, plain, "alice", other); !errors.Is(err, ErrKeyringNotVerified) {
+		t.Errorf("UnlockKeyring() of a replaced keyring = %v; want ErrKeyringNotVerified", err)
+	}
+	//on a new computer the data key must open the sealed secrets before it is pinned
+	os.Remove(pinFile)
+	if _, err := UnlockKeyring(ctx, plain, "alice", other); !errors.Is(err, ErrKeyringNotVerified) {
+		t.Errorf("UnlockKeyring() of a replaced keyring = %v; want ErrKeyringNotVerified", err)
+	}
+	_, version, _ = readKeyring(ctx, plain, "alice")
+	writeKeyring(ctx, plain, "alice", keyring, version)
+	if key, err := UnlockKeyring(ctx, plain, "alice", other); err != nil || !bytes.Equal(key, dataKey) {
+		t.Errorf("UnlockKeyring() on a new computer = %v; want the data key", err)
+	}
+	if _, err := os.Stat(pinFile); err != nil {
+		t.Errorf("UnlockKeyring() did not pin the data key: %v", err)
+	}
+}
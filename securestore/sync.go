@@ -0,0 +1,155 @@
+package securestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/abruno06/myvault/secret"
+)
+
+// the difference of a secret between the source and the target store
+const (
+	SyncAdded   = "added"   // only in the source
+	SyncChanged = "changed" // in both stores with a different content
+	SyncRemoved = "removed" // only in the target
+)
+
+// what the sync does with a difference
+const (
+	SyncToTarget     = "copy to target"
+	SyncToSource     = "copy to source"
+	SyncDeleteTarget = "delete from target"
+	SyncSkip         = "skip"
+	SyncConflict     = "conflict"
+)
+
+// SyncChange is a difference between two stores and the action taken to resolve it
+type SyncChange struct {
+	SecretID string
+	Kind     string
+	Action   string
+}
+
+// SyncOptions select how SyncStores resolve the differences
+// one way copy the source to the target (Prune also delete the secrets missing from the source)
+// two way copy the missing secrets both ways and keep the latest LastUpdate for the changed ones
+type SyncOptions struct {
+	TwoWay bool
+	Prune  bool
+	DryRun bool
+}
+
+// this function will return the added, changed and removed secretIDs of target compared to source
+func CompareStores(ctx context.Context, source, target SecretStore) ([]SyncChange, error) {
+	src, err := getFolderSecrets(ctx, source, "")
+	if err != nil {
+		return nil, fmt.Errorf("reading source: %w", err)
+	}
+	dst, err := getFolderSecrets(ctx, target, "")
+	if err != nil {
+		return nil, fmt.Errorf("reading target: %w", err)
+	}
+	var rValue []SyncChange
+	for id, s := range src {
+		d, ok := dst[id]
+		if !ok {
+			rValue = append(rValue, SyncChange{SecretID: id, Kind: SyncAdded})
+		} else if !s.Equal(d) {
+			rValue = append(rValue, SyncChange{SecretID: id, Kind: SyncChanged})
+		}
+	}
+	for id := range dst {
+		if _, ok := src[id]; !ok {
+			rValue = append(rValue, SyncChange{SecretID: id, Kind: SyncRemoved})
+		}
+	}
+	sort.Slice(rValue, func(i, j int) bool {
+		return rValue[i].SecretID < rValue[j].SecretID
+	})
+	return rValue, nil
+}
+
+// choose the action for a difference
+func syncAction(change SyncChange, src, dst secret.Secret, options SyncOptions) string {
+	switch change.Kind {
+	case SyncAdded:
+		return SyncToTarget
+	case SyncRemoved:
+		if options.TwoWay {
+			return SyncToSource
+		}
+		if options.Prune {
+			return SyncDeleteTarget
+		}
+		return SyncSkip
+	}
+	if !options.TwoWay {
+		return SyncToTarget
+	}
+	// the latest update win, the same LastUpdate with a different content can not be resolved
+	switch {
+	case src.LastUpdate.After(dst.LastUpdate):
+		return SyncToTarget
+	case dst.LastUpdate.After(src.LastUpdate):
+		return SyncToSource
+	}
+	return SyncConflict
+}
+
+// this function will compare the stores and apply the changes selected by the options
+// with DryRun nothing is written, the returned changes show what would be done
+func SyncStores(ctx context.Context, source, target SecretStore, options SyncOptions) ([]SyncChange, error) {
+	changes, err := CompareStores(ctx, source, target)
+	if err != nil {
+		return nil, err
+	}
+	for i, change := range changes {
+		src, _, err := readSecret(ctx, source, change.SecretID)
+		if err != nil {
+			return changes, err
+		}
+		dst, _, err := readSecret(ctx, target, change.SecretID)
+		if err != nil {
+			return changes, err
+		}
+		changes[i].Action = syncAction(change, src, dst, options)
+		if options.DryRun {
+			continue
+		}
+		//the chunks of the attachments are copied before the secret referencing them
+		switch changes[i].Action {
+		case SyncToTarget:
+			if err = copyAttachmentChunks(ctx, source, target, src); err == nil {
+				err = AddSecret(ctx, target, src, change.SecretID)
+			}
+		case SyncToSource:
+			if err = copyAttachmentChunks(ctx, target, source, dst); err == nil {
+				err = AddSecret(ctx, source, dst, change.SecretID)
+			}
+		case SyncDeleteTarget:
+			err = DeleteSecret(ctx, target, change.SecretID)
+		}
+		if err != nil {
+			return changes, fmt.Errorf("Secret ID: %s %s: %w", change.SecretID, changes[i].Action, err)
+		}
+	}
+	return changes, nil
+}
+
+// this function display the changes in tabular format
+func DisplaySyncChanges(changes []SyncChange) {
+	if len(changes) == 0 {
+		fmt.Println("Stores are in sync")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	format := "%s\t%s\t%s\n"
+	fmt.Fprintf(w, format, "ID", "Difference", "Action")
+	for _, c := range changes {
+		fmt.Fprintf(w, format, c.SecretID, c.Kind, c.Action)
+	}
+	w.Flush()
+}
@@ -0,0 +1,66 @@
+package securestore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/abruno06/myvault/secret"
+)
+
+// this function will return the secrets carrying the tag
+func getTaggedSecrets(ctx context.Context, secstore SecretStore, tag string) (map[string]secret.Secret, error) {
+	secrets, err := getFolderSecrets(ctx, secstore, "")
+	if err != nil {
+		return nil, err
+	}
+	for id, s := range secrets {
+		if !s.HasTag(tag) {
+			delete(secrets, id)
+		}
+	}
+	return secrets, nil
+}
+
+// this function list the secrets carrying the tag in tabular format
+func ListTag(ctx context.Context, secstore SecretStore, tag string) error {
+	secrets, err := getTaggedSecrets(ctx, secstore, tag)
+	if err != nil {
+		return err
+	}
+	displaySecrets(secstore, secrets)
+	return nil
+}
+
+// this function will return all the tags used with the number of secrets carrying them
+func ListTags(ctx context.Context, secstore SecretStore) (map[string]int, error) {
+	secrets, err := getFolderSecrets(ctx, secstore, "")
+	if err != nil {
+		return nil, err
+	}
+	rValue := make(map[string]int)
+	for _, s := range secrets {
+		for _, tag := range s.Tags {
+			rValue[tag]++
+		}
+	}
+	return rValue, nil
+}
+
+// this function will wrap every secret carrying the tag into one cubbyhole and return the token
+func WrapSecretTag(ctx context.Context, secstore SecretStore, tag string, storePath string, ttl time.Duration) (string, error) {
+	secrets, err := getTaggedSecrets(ctx, secstore, tag)
+	if err != nil {
+		return "", err
+	}
+	if len(secrets) == 0 {
+		return "", fmt.Errorf("no secret tagged %s", tag)
+	}
+	var ids []string
+	for id := range secrets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return WrapSecretList(ctx, secstore, ids, storePath, ttl)
+}
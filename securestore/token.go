@@ -3,6 +3,7 @@ package securestore
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -13,8 +14,19 @@ import (
 	"github.com/hashicorp/vault-client-go/schema"
 )
 
+// the token functions are only available when connected to vault
+func requireVault(secstore SecretStore) error {
+	if secstore.Client == nil {
+		return errors.New("tokens are only available with the vault backend")
+	}
+	return nil
+}
+
 // create a new token with a given policy
 func CreateToken(ctx context.Context, secstore SecretStore, policies []string) (string, error) {
+	if err := requireVault(secstore); err != nil {
+		return "", err
+	}
 	//extract the client from the SecretStore
 	client := secstore.Client
 	//extract the mountpath from the SecretStore
@@ -37,63 +49,47 @@ func CreateToken(ctx context.Context, secstore SecretStore, policies []string) (
 
 // this function will wrap a token and return the wrapped token
 func WrapToken(ctx context.Context, secstore SecretStore, token string, ttl time.Duration) (string, error) {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
 	//generate a UUID for the wrapping token
 	uuid := uuid.New().String()
 	//set the token into a cubbyhole entry
-	_, err := client.Secrets.CubbyholeWrite(ctx, uuid, map[string]interface{}{"token": token}, vault.WithMountPath(mountpath))
+	err := secstore.Backend.WriteCubbyhole(ctx, uuid, map[string]interface{}{"token": token})
 	if err != nil {
 		log.Fatal(err)
 	}
 	//wrap the cubbyhole
-	resp, err := client.Secrets.CubbyholeRead(ctx, uuid, vault.WithMountPath(mountpath), vault.WithResponseWrapping(ttl))
+	wToken, err := secstore.Backend.Wrap(ctx, uuid, ttl)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//fmt.Printf("Wrapped response: %v\n", resp.WrapInfo)
-	return resp.WrapInfo.Token, err
+	return wToken, err
 }
 
 // this function will unwrap a token and return the Access token from it
 func UnWrappeToken(ctx context.Context, secstore SecretStore, token string) (string, error) {
-	//extract the client from the SecretStore
-	client := secstore.Client
-
 	//read the cubbyhole
-	resp, err := vault.Unwrap[map[string]interface{}](ctx, client, token)
+	data, err := secstore.Backend.Unwrap(ctx, token)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//fmt.Printf("Unwrapped response: %v\n", resp)
-	// loop to the resp.Data key
-	return resp.Data["token"].(string), err
+	return data["token"].(string), err
 }
 
 // this function will retur all Cubbyhole entries
 func ListCubbyhole(ctx context.Context, secstore SecretStore) (string, error) {
-	//extract the client from the SecretStore
-	client := secstore.Client
-	//extract the mountpath from the SecretStore
-	mountpath := secstore.Mountpath
-
 	//read the cubbyhole
-	resp, err := client.Secrets.CubbyholeList(ctx, "", vault.WithMountPath(mountpath))
+	keys, err := secstore.Backend.ListCubbyhole(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//fmt.Printf("Cubbyhole response: %v\n", resp.Data.Keys)
 	rValue := make(map[string]secret.Secret)
-	for _, v := range resp.Data.Keys {
+	for _, v := range keys {
 		//get the cubbyhole entry value
-		value, err := client.Secrets.CubbyholeRead(ctx, v, vault.WithMountPath(mountpath))
+		value, err := secstore.Backend.ReadCubbyhole(ctx, v)
 		if err != nil {
 			log.Fatal(err)
 		}
 		//convert the value to secret
-		rValue[v], _ = secret.ConvertToSecret(value.Data)
+		rValue[v], _ = secret.ConvertToSecret(value)
 
 	}
 	//fmt.Printf("Cubbyhole response: %v\n", rValue)
@@ -104,6 +100,9 @@ func ListCubbyhole(ctx context.Context, secstore SecretStore) (string, error) {
 
 // function renew the token
 func RenewToken(ctx context.Context, secstore SecretStore, token string) error {
+	if err := requireVault(secstore); err != nil {
+		return err
+	}
 	//extract the client from the SecretStore
 	client := secstore.Client
 	//renew the token
@@ -118,6 +117,9 @@ func RenewToken(ctx context.Context, secstore SecretStore, token string) error {
 
 // function to revoke the token
 func RevokeToken(ctx context.Context, secstore SecretStore, token string) error {
+	if err := requireVault(secstore); err != nil {
+		return err
+	}
 	//extract the client from the SecretStore
 	client := secstore.Client
 	//revoke the token
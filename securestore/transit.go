@@ -0,0 +1,365 @@
+package securestore
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/abruno06/myvault/secret"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+)
+
+// With TRANSITKEY set the sensitive fields are encrypted by the vault Transit engine before they are written to KV,
+// a token reading the KV entries without the right to decrypt with the key only gets "vault:v1:..." ciphertexts
+// the sensitive fields are the secret fields of the type (Credential, OTP...), the attachments and the attachment chunks
+// an encrypted entry carries the name of the key in its Transit field, the entries without it are plaintext even when
+// a value looks like a ciphertext (a credential written before transit can start with "vault:v")
+
+// the prefix of the values encrypted by transit
+const transitPrefix = "vault:v"
+
+// the field of the entries encrypted by transit
+const transitField = "Transit"
+
+// TransitBackend encrypt the sensitive fields of the entries of a Backend with a Transit key
+type TransitBackend struct {
+	Backend
+	Client *vault.Client
+	// Mountpath is the mount of the transit engine and Key the name of the transit key
+	Mountpath string
+	Key       string
+}
+
+// ErrTransitDisabled is returned by the transit commands when the store does not use transit
+var ErrTransitDisabled = errors.New("transit encryption is not enabled (TRANSITKEY)")
+
+// check if the key of the entry is a sensitive field, the ones encrypted by transit or the zero knowledge key
+func sensitiveField(data map[string]interface{}, key string) bool {
+	typeName, _ := data["Type"].(string)
+	// Data is the content of an attachment chunk
+	return key == "Attachments" || key == "Data" || secret.IsSecretField(typeName, key)
+}
+
+// check if the value is a transit ciphertext
+func isTransitCiphertext(value string) bool {
+	return strings.HasPrefix(value, transitPrefix)
+}
+
+// return the key version of a transit ciphertext ("vault:v3:..." is "v3")
+func transitKeyVersion(ciphertext string) string {
+	version, _, _ := strings.Cut(strings.TrimPrefix(ciphertext, "vault:"), ":")
+	return version
+}
+
+// this function will encrypt the value with the transit key
+func (b *TransitBackend) encrypt(ctx context.Context, plaintext string) (string, error) {
+	resp, err := b.Client.Secrets.TransitEncrypt(ctx, b.Key, schema.TransitEncryptRequest{Plaintext: base64.StdEncoding.EncodeToString([]byte(plaintext))}, vault.WithMountPath(b.Mountpath))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, _ := resp.Data["ciphertext"].(string)
+	if !isTransitCiphertext(ciphertext) {
+		return "", fmt.Errorf("transit key %s returned no ciphertext", b.Key)
+	}
+	return ciphertext, nil
+}
+
+// this function will decrypt the transit ciphertext
+func (b *TransitBackend) decrypt(ctx context.Context, ciphertext string) (string, error) {
+	resp, err := b.Client.Secrets.TransitDecrypt(ctx, b.Key, schema.TransitDecryptRequest{Ciphertext: ciphertext}, vault.WithMountPath(b.Mountpath))
+	if err != nil {
+		return "", err
+	}
+	encoded, _ := resp.Data["plaintext"].(string)
+	plaintext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("transit key %s returned an invalid plaintext: %v", b.Key, err)
+	}
+	return string(plaintext), nil
+}
+
+// this function will encrypt the ciphertext again with the latest version of the transit key
+func (b *TransitBackend) rewrap(ctx context.Context, ciphertext string) (string, error) {
+	resp, err := b.Client.Secrets.TransitRewrap(ctx, b.Key, schema.TransitRewrapRequest{Ciphertext: ciphertext}, vault.WithMountPath(b.Mountpath))
+	if err != nil {
+		return "", err
+	}
+	rewrapped, _ := resp.Data["ciphertext"].(string)
+	if !isTransitCiphertext(rewrapped) {
+		return "", fmt.Errorf("transit key %s returned no ciphertext", b.Key)
+	}
+	return rewrapped, nil
+}
+
+// return a copy of the entry where the sensitive string values are replaced by convert, changed is false
+// when convert kept all the values
+func convertFields(data map[string]interface{}, convert func(value string) (string, error)) (map[string]interface{}, bool, error) {
+	rValue := make(map[string]interface{}, len(data))
+	changed := false
+	for k, v := range data {
+		rValue[k] = v
+		value, ok := v.(string)
+		if !ok || value == "" || !sensitiveField(data, k) {
+			continue
+		}
+		converted, err := convert(value)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", k, err)
+		}
+		rValue[k] = converted
+		changed = changed || converted != value
+	}
+	return rValue, changed, nil
+}
+
+// an entryConverter return a copy of the entry with its sensitive values converted, changed is false when the
+// entry is kept as is
+type entryConverter func(data map[string]interface{}) (map[string]interface{}, bool, error)
+
+// check if the entry was encrypted by transit
+func isTransitEntry(data map[string]interface{}) bool {
+	_, ok := data[transitField]
+	return ok
+}
+
+// the sensitive fields are decrypted, the entries written before transit was enabled are kept
+func (b *TransitBackend) Get(ctx context.Context, path string, version int64) (map[string]interface{}, int64, error) {
+	data, version, err := b.Backend.Get(ctx, path, version)
+	if err != nil || !isTransitEntry(data) {
+		return data, version, err
+	}
+	data, _, err = convertFields(data, func(value string) (string, error) {
+		if !isTransitCiphertext(value) {
+			return value, nil
+		}
+		return b.decrypt(ctx, value)
+	})
+	delete(data, transitField)
+	return data, version, err
+}
+
+// return the entry with its sensitive fields encrypted and marked with the key, an entry without sensitive values
+// is kept as is
+func (b *TransitBackend) encryptEntry(ctx context.Context, data map[string]interface{}) (map[string]interface{}, bool, error) {
+	data, changed, err := convertFields(data, func(value string) (string, error) {
+		return b.encrypt(ctx, value)
+	})
+	if changed {
+		data[transitField] = b.Key
+	}
+	return data, changed, err
+}
+
+// the sensitive fields are encrypted before they are written
+func (b *TransitBackend) Put(ctx context.Context, path string, data map[string]interface{}, cas int64) (int64, error) {
+	data, _, err := b.encryptEntry(ctx, data)
+	if err != nil {
+		return 0, err
+	}
+	return b.Backend.Put(ctx, path, data, cas)
+}
+
+// return the transit backend of the store
+func transitBackend(secstore SecretStore) (*TransitBackend, error) {
+	b, ok := secstore.Backend.(*TransitBackend)
+	if !ok {
+		return nil, ErrTransitDisabled
+	}
+	return b, nil
+}
+
+// return the latest version of the entry when it is soft deleted (in the trash), 0 otherwise
+func trashedVersion(ctx context.Context, backend Backend, path string) (int64, error) {
+	if !backend.Versioned() {
+		return 0, nil
+	}
+	versions, err := backend.Versions(ctx, path)
+	if errors.Is(err, ErrNotFound) || len(versions) == 0 {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	latest := versions[len(versions)-1]
+	if latest.DeletionTime == "" || latest.Destroyed {
+		return 0, nil
+	}
+	return latest.Version, nil
+}
+
+// write a new version of the entry stored at path in the backend when convert changes it
+// when destroy is set the older versions still holding the values replaced are destroyed
+// an entry in the trash is restored to be rewritten then deleted again
+func rewriteEntry(ctx context.Context, backend Backend, path string, convert entryConverter, destroy bool) (bool, error) {
+	trashed, err := trashedVersion(ctx, backend, path)
+	if err != nil {
+		return false, err
+	}
+	if trashed == 0 {
+		changed, _, err := rewriteLatest(ctx, backend, path, convert, destroy)
+		return changed, err
+	}
+	if err := backend.Undelete(ctx, path, trashed); err != nil {
+		return false, err
+	}
+	changed, version, err := rewriteLatest(ctx, backend, path, convert, destroy)
+	if version == 0 {
+		version = trashed
+	}
+	if derr := backend.Delete(ctx, path, version); err == nil {
+		err = derr
+	}
+	return changed, err
+}
+
+// rewrite the latest version of the entry, return the version read or written (0 when the entry can not be read)
+func rewriteLatest(ctx context.Context, backend Backend, path string, convert entryConverter, destroy bool) (bool, int64, error) {
+	data, version, err := backend.Get(ctx, path, 0)
+	if errors.Is(err, ErrNotFound) {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	data, changed, err := convert(data)
+	if err != nil {
+		return false, version, err
+	}
+	if changed {
+		written, err := backend.Put(ctx, path, data, version)
+		if err != nil {
+			return false, version, err
+		}
+		version = written
+	}
+	if !destroy {
+		return changed, version, nil
+	}
+	return changed, version, destroyStaleVersions(ctx, backend, path, version, convert)
+}
+
+// destroy the versions of the entry older than current that convert would change or that can not be read
+// (soft deleted), otherwise the history keeps the plaintext or the old ciphertexts readable
+func destroyStaleVersions(ctx context.Context, backend Backend, path string, current int64, convert entryConverter) error {
+	if !backend.Versioned() {
+		return nil
+	}
+	versions, err := backend.Versions(ctx, path)
+	if err != nil {
+		return err
+	}
+	var stale []int64
+	for _, v := range versions {
+		if v.Version >= current || v.Destroyed {
+			continue
+		}
+		if v.DeletionTime == "" {
+			data, _, err := backend.Get(ctx, path, v.Version)
+			if err != nil {
+				return err
+			}
+			_, changed, err := convert(data)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+		}
+		stale = append(stale, v.Version)
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return backend.DestroyVersions(ctx, path, stale)
+}
+
+// rewrite every secret of the application and their attachment chunks in the backend wrapped by the store backend
+// destroy is only set by the plaintext migrations, the history of the other rewrites is kept
+// return the number of entries written
+func rewriteEntries(ctx context.Context, secstore SecretStore, backend Backend, convert entryConverter, destroy bool) (int, error) {
+	ids, err := listSecretIDs(ctx, secstore, "")
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, id := range ids {
+		paths := []string{secretPath(secstore, id)}
+		//the chunks of the attachments are separate entries, the ones of the versions in the trash are included
+		attachments, err := versionsAttachments(ctx, secstore, id, true)
+		if err != nil {
+			return count, fmt.Errorf("Secret ID: %s %w", id, err)
+		}
+		for _, a := range attachments {
+			for n := 0; n < a.Chunks; n++ {
+				paths = append(paths, attachmentChunkPath(secstore, a.ChunkID, n))
+			}
+		}
+		for _, path := range paths {
+			written, err := rewriteEntry(ctx, backend, path, convert, destroy)
+			if err != nil {
+				return count, fmt.Errorf("Secret ID: %s %w", id, err)
+			}
+			if written {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// this function will encrypt the sensitive fields of the entries written before transit was enabled
+// return the number of entries written
+func TransitEncryptExisting(ctx context.Context, secstore SecretStore) (int, error) {
+	b, err := transitBackend(secstore)
+	if err != nil {
+		return 0, err
+	}
+	return rewriteEntries(ctx, secstore, b.Backend, func(data map[string]interface{}) (map[string]interface{}, bool, error) {
+		if isTransitEntry(data) {
+			return data, false, nil
+		}
+		return b.encryptEntry(ctx, data)
+	}, true)
+}
+
+// this function will rewrap the sensitive fields with the latest version of the transit key (after a key rotation)
+// only the entries using an older key version are written, their older versions are kept (retire the old key versions
+// with min_decryption_version), return the number of entries written
+func TransitRewrap(ctx context.Context, secstore SecretStore) (int, error) {
+	b, err := transitBackend(secstore)
+	if err != nil {
+		return 0, err
+	}
+	return rewriteEntries(ctx, secstore, b.Backend, func(data map[string]interface{}) (map[string]interface{}, bool, error) {
+		if !isTransitEntry(data) {
+			return data, false, nil
+		}
+		return convertFields(data, func(value string) (string, error) {
+			if !isTransitCiphertext(value) {
+				return value, nil
+			}
+			rewrapped, err := b.rewrap(ctx, value)
+			if err != nil || transitKeyVersion(rewrapped) == transitKeyVersion(value) {
+				return value, err
+			}
+			return rewrapped, nil
+		})
+	}, false)
+}
+
+// this function will rotate the transit key then rewrap the entries, return the number of entries written
+func TransitRotate(ctx context.Context, secstore SecretStore) (int, error) {
+	b, err := transitBackend(secstore)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := b.Client.Secrets.TransitRotateKey(ctx, b.Key, schema.TransitRotateKeyRequest{}, vault.WithMountPath(b.Mountpath)); err != nil {
+		return 0, err
+	}
+	return TransitRewrap(ctx, secstore)
+}
@@ -0,0 +1,146 @@
+package securestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abruno06/myvault/config"
+)
+
+// A deleted secret is a secret whose latest version is soft deleted, it stays in the trash
+// until it is restored or purged, once its retention period (config TRASHRETENTION) is over it can only be purged
+
+// TrashEntry describe a deleted secret
+type TrashEntry struct {
+	SecretID  string
+	Version   int64
+	DeletedAt time.Time
+	// ExpireAt is the end of the retention period, Expired is set when it is over
+	ExpireAt time.Time
+	Expired  bool
+}
+
+// this function will return the trash entry of the secretID, ok is false if the secret is not deleted
+func readTrashEntry(ctx context.Context, secstore SecretStore, secretID string, retention time.Duration) (TrashEntry, bool, error) {
+	versions, err := ListSecretVersions(ctx, secstore, secretID)
+	if err != nil || len(versions) == 0 {
+		return TrashEntry{}, false, err
+	}
+	latest := versions[len(versions)-1]
+	if latest.DeletionTime == "" || latest.Destroyed {
+		return TrashEntry{}, false, nil
+	}
+	deletedAt, err := time.Parse(time.RFC3339Nano, latest.DeletionTime)
+	if err != nil {
+		return TrashEntry{}, false, err
+	}
+	expireAt := deletedAt.Add(retention)
+	return TrashEntry{SecretID: secretID, Version: latest.Version, DeletedAt: deletedAt, ExpireAt: expireAt, Expired: time.Now().After(expireAt)}, true, nil
+}
+
+// this function will return the deleted secrets, most recently deleted first
+// entries whose retention is over are flagged Expired, they are only removed by PurgeExpiredTrash
+func ListTrash(ctx context.Context, secstore SecretStore) ([]TrashEntry, error) {
+	if !secstore.Backend.Versioned() {
+		return nil, fmt.Errorf("trash: %w", ErrNotSupported)
+	}
+	retention := config.ReadTrashRetention()
+	keys, err := listSecretIDs(ctx, secstore, "")
+	if err != nil {
+		return nil, err
+	}
+	var rValue []TrashEntry
+	for _, k := range keys {
+		entry, ok, err := readTrashEntry(ctx, secstore, k, retention)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		rValue = append(rValue, entry)
+	}
+	sort.Slice(rValue, func(i, j int) bool {
+		return rValue[i].DeletedAt.After(rValue[j].DeletedAt)
+	})
+	return rValue, nil
+}
+
+// this function will display the trash in tabular format
+func DisplayTrash(ctx context.Context, secstore SecretStore) error {
+	entries, err := ListTrash(ctx, secstore)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	format := "%s\t%s\t%s\t%s\t%s\n"
+	fmt.Fprintf(w, format, "ID", "Version", "Deleted", "Retention until", "Expired")
+	for _, e := range entries {
+		expired := ""
+		if e.Expired {
+			expired = "yes"
+		}
+		fmt.Fprintf(w, format, e.SecretID, strconv.FormatInt(e.Version, 10), e.DeletedAt.UTC().Format("2006-01-02 15:04:05"), e.ExpireAt.UTC().Format("2006-01-02 15:04:05"), expired)
+	}
+	w.Flush()
+	return nil
+}
+
+// this function will restore a deleted secret by undeleting its latest version
+func UndeleteSecret(ctx context.Context, secstore SecretStore, secretID string) error {
+	entry, ok, err := readTrashEntry(ctx, secstore, secretID, config.ReadTrashRetention())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("Secret ID: %s is not in the trash", secretID)
+	}
+	if entry.Expired {
+		return fmt.Errorf("Secret ID: %s retention is over", secretID)
+	}
+	return secstore.Backend.Undelete(ctx, secretPath(secstore, secretID), entry.Version)
+}
+
+// this function will permanently remove a deleted secret, all its versions and the chunks of their attachments,
+// this can not be undone
+func PurgeSecret(ctx context.Context, secstore SecretStore, secretID string) error {
+	_, ok, err := readTrashEntry(ctx, secstore, secretID, config.ReadTrashRetention())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("Secret ID: %s is not in the trash", secretID)
+	}
+	attachments, err := versionsAttachments(ctx, secstore, secretID, true)
+	if err != nil {
+		return err
+	}
+	if err := secstore.Backend.Destroy(ctx, secretPath(secstore, secretID)); err != nil {
+		return err
+	}
+	return destroyOrphanChunks(ctx, secstore, secretID, attachments)
+}
+
+// this function will permanently remove the deleted secrets whose retention is over, return the IDs purged
+func PurgeExpiredTrash(ctx context.Context, secstore SecretStore) ([]string, error) {
+	entries, err := ListTrash(ctx, secstore)
+	if err != nil {
+		return nil, err
+	}
+	var rValue []string
+	for _, e := range entries {
+		if !e.Expired {
+			continue
+		}
+		if err := PurgeSecret(ctx, secstore, e.SecretID); err != nil {
+			return rValue, err
+		}
+		rValue = append(rValue, e.SecretID)
+	}
+	return rValue, nil
+}
@@ -0,0 +1,376 @@
+package securestore
+
+import (
+	"context"
+	gocrypto "crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/abruno06/myvault/config"
+	"github.com/abruno06/myvault/crypto"
+)
+
+// With ZEROKNOWLEDGE the sensitive fields are encrypted on the client with a data key of the user before they are written,
+// vault only keeps "myvault:v1:..." ciphertexts so its admins can not read the credentials
+// the data key is stored in <APPNAME>.keyring/<user> wrapped to the public key of the Yubikey key management slot
+// (the PIN is needed to unwrap it) and sealed with a recovery code for the day the Yubikey is lost
+// vault admins can write the keyring, the fingerprint of the data key is pinned in KEYRINGPINFILE so a keyring
+// replaced by one wrapping another data key is rejected; on a new computer the data key must open the sealed entries
+// before it is pinned
+// a sealed entry carries its Sealed field, the entries without it are plaintext even when a value looks like a
+// ciphertext (a credential written before ZEROKNOWLEDGE can start with "myvault:v1:")
+
+// the prefix of the values encrypted with the data key
+const sealedPrefix = "myvault:v1:"
+
+// the field of the entries encrypted with the data key, its value is the version of the format
+const sealedField = "Sealed"
+
+// SealedBackend encrypt the sensitive fields of the entries of a Backend with the data key of the user
+type SealedBackend struct {
+	Backend
+	Key []byte
+}
+
+// ErrKeyringNotFound is returned when the user has no zero knowledge keyring yet
+var ErrKeyringNotFound = errors.New("zero knowledge keyring not found")
+
+// ErrZeroKnowledgeDisabled is returned when the store is not unlocked with a data key
+var ErrZeroKnowledgeDisabled = errors.New("zero knowledge encryption is not enabled (ZEROKNOWLEDGE)")
+
+// ErrKeyringNotVerified is returned when the data key of the keyring is not the one pinned on this computer or does
+// not open the sealed entries
+var ErrKeyringNotVerified = errors.New("zero knowledge keyring does not verify")
+
+// ErrKeyringMissing is returned when a keyring is created while entries are sealed with a data key
+var ErrKeyringMissing = errors.New("secrets are sealed with a data key but the zero knowledge keyring is missing, restore it")
+
+// Keyring is the data key of a user, wrapped to the key management public key and sealed with the recovery code
+type Keyring struct {
+	WrappedKey   crypto.WrappedKey `json:"wrappedkey"`
+	RecoverySalt []byte            `json:"recoverysalt"`
+	Recovery     []byte            `json:"recovery"`
+}
+
+// return the path of the keyring of the user
+func keyringPath(secstore SecretStore, user string) string {
+	return secstore.Appname + ".keyring/" + user
+}
+
+// this function will return the store encrypting the sensitive fields with the data key
+func SealedStore(secstore SecretStore, dataKey []byte) SecretStore {
+	secstore.Backend = &SealedBackend{Backend: secstore.Backend, Key: dataKey}
+	return secstore
+}
+
+// check if the value is encrypted with a data key
+func isSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// this function will encrypt the value with the data key
+func (b *SealedBackend) seal(plaintext string) (string, error) {
+	sealed, err := crypto.Seal(b.Key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// this function will decrypt a value encrypted with the data key
+func (b *SealedBackend) open(value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := crypto.Open(b.Key, sealed)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt with the zero knowledge key: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// check if the entry was encrypted with a data key
+func isSealedEntry(data map[string]interface{}) bool {
+	_, ok := data[sealedField]
+	return ok
+}
+
+// the sensitive fields are decrypted, the entries written before ZEROKNOWLEDGE was enabled are kept
+func (b *SealedBackend) Get(ctx context.Context, path string, version int64) (map[string]interface{}, int64, error) {
+	data, version, err := b.Backend.Get(ctx, path, version)
+	if err != nil || !isSealedEntry(data) {
+		return data, version, err
+	}
+	data, _, err = convertFields(data, func(value string) (string, error) {
+		if !isSealed(value) {
+			return value, nil
+		}
+		return b.open(value)
+	})
+	delete(data, sealedField)
+	return data, version, err
+}
+
+// return the entry with its sensitive fields encrypted and marked, an entry without sensitive values is kept as is
+func (b *SealedBackend) sealEntry(data map[string]interface{}) (map[string]interface{}, bool, error) {
+	data, changed, err := convertFields(data, b.seal)
+	if changed {
+		data[sealedField] = "v1"
+	}
+	return data, changed, err
+}
+
+// the sensitive fields are encrypted before they are written
+func (b *SealedBackend) Put(ctx context.Context, path string, data map[string]interface{}, cas int64) (int64, error) {
+	data, _, err := b.sealEntry(data)
+	if err != nil {
+		return 0, err
+	}
+	return b.Backend.Put(ctx, path, data, cas)
+}
+
+// read the keyring of the user and its version
+func readKeyring(ctx context.Context, secstore SecretStore, user string) (Keyring, int64, error) {
+	data, version, err := secstore.Backend.Get(ctx, keyringPath(secstore, user), 0)
+	if errors.Is(err, ErrNotFound) {
+		return Keyring{}, 0, ErrKeyringNotFound
+	}
+	if err != nil {
+		return Keyring{}, 0, err
+	}
+	var rValue Keyring
+	value, _ := data["Keyring"].(string)
+	if err := json.Unmarshal([]byte(value), &rValue); err != nil {
+		return Keyring{}, 0, fmt.Errorf("keyring of %s is not valid: %v", user, err)
+	}
+	return rValue, version, nil
+}
+
+// write the keyring of the user, cas is the version read (0 to create it)
+func writeKeyring(ctx context.Context, secstore SecretStore, user string, keyring Keyring, cas int64) error {
+	jsonData, err := json.Marshal(keyring)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{"Keyring": string(jsonData), "LastUpdate": time.Now().UTC().Format(time.RFC3339)}
+	_, err = secstore.Backend.Put(ctx, keyringPath(secstore, user), data, cas)
+	return err
+}
+
+// return the name of the keyring in the pin file
+func keyringPinName(secstore SecretStore, user string) string {
+	return path.Join(secstore.Namespace, secstore.Mountpath, keyringPath(secstore, user))
+}
+
+// return the fingerprint of the data key pinned on this computer
+func keyringFingerprint(dataKey []byte) string {
+	sum := sha256.Sum256(dataKey)
+	return hex.EncodeToString(sum[:])
+}
+
+// read the fingerprints pinned on this computer, an empty map if the file does not exist yet
+func readKeyringPins() (map[string]string, error) {
+	pins := make(map[string]string)
+	raw, err := os.ReadFile(config.ReadKeyringPinFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return pins, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &pins); err != nil {
+		return nil, fmt.Errorf("keyring pin file is not valid: %v", err)
+	}
+	return pins, nil
+}
+
+// pin the fingerprint of the data key of the user on this computer, the file is only readable by its owner (0600)
+func pinKeyring(secstore SecretStore, user string, dataKey []byte) error {
+	pins, err := readKeyringPins()
+	if err != nil {
+		return err
+	}
+	pins[keyringPinName(secstore, user)] = keyringFingerprint(dataKey)
+	raw, err := json.Marshal(pins)
+	if err != nil {
+		return err
+	}
+	file := config.ReadKeyringPinFile()
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// return the paths of the secrets sealed with a data key (the latest version of each secret)
+func sealedEntries(ctx context.Context, secstore SecretStore) ([]string, error) {
+	ids, err := listSecretIDs(ctx, secstore, "")
+	if err != nil {
+		return nil, err
+	}
+	var rValue []string
+	for _, id := range ids {
+		path := secretPath(secstore, id)
+		data, _, err := secstore.Backend.Get(ctx, path, 0)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isSealedEntry(data) {
+			rValue = append(rValue, path)
+		}
+	}
+	return rValue, nil
+}
+
+// check the data key unwrapped from the keyring: it must be the one pinned on this computer, the first time it must
+// open the sealed entries and it is pinned
+func verifyKeyring(ctx context.Context, secstore SecretStore, user string, dataKey []byte) error {
+	pins, err := readKeyringPins()
+	if err != nil {
+		return err
+	}
+	if pinned, ok := pins[keyringPinName(secstore, user)]; ok {
+		if pinned != keyringFingerprint(dataKey) {
+			return fmt.Errorf("%w: the data key is not the one pinned in %s", ErrKeyringNotVerified, config.ReadKeyringPinFile())
+		}
+		return nil
+	}
+	paths, err := sealedEntries(ctx, secstore)
+	if err != nil {
+		return err
+	}
+	sealed := SealedStore(secstore, dataKey)
+	for _, path := range paths {
+		if _, _, err := sealed.Backend.Get(ctx, path, 0); err != nil {
+			return fmt.Errorf("%w: %s %v", ErrKeyringNotVerified, path, err)
+		}
+	}
+	return pinKeyring(secstore, user, dataKey)
+}
+
+// this function will create the keyring of the user: a new data key wrapped to the public key and sealed with a new
+// recovery code, the data key and the recovery code are returned, the recovery code is not stored anywhere
+// the keyring is not created when secrets are already sealed (their keyring was removed)
+func InitKeyring(ctx context.Context, secstore SecretStore, user string, pub gocrypto.PublicKey) ([]byte, string, error) {
+	if _, _, err := readKeyring(ctx, secstore, user); !errors.Is(err, ErrKeyringNotFound) {
+		if err == nil {
+			err = fmt.Errorf("keyring of %s already exists", user)
+		}
+		return nil, "", err
+	}
+	paths, err := sealedEntries(ctx, secstore)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(paths) > 0 {
+		return nil, "", fmt.Errorf("%w (%d sealed secrets)", ErrKeyringMissing, len(paths))
+	}
+	dataKey, err := crypto.NewDataKey()
+	if err != nil {
+		return nil, "", err
+	}
+	var keyring Keyring
+	if keyring.WrappedKey, err = crypto.WrapKey(pub, dataKey); err != nil {
+		return nil, "", err
+	}
+	code, err := crypto.NewRecoveryCode()
+	if err != nil {
+		return nil, "", err
+	}
+	if keyring.RecoverySalt, err = crypto.NewSalt(); err != nil {
+		return nil, "", err
+	}
+	normalized, _ := crypto.NormalizeRecoveryCode(code)
+	if keyring.Recovery, err = crypto.Seal(crypto.DeriveKey(normalized, keyring.RecoverySalt), dataKey); err != nil {
+		return nil, "", err
+	}
+	if err := writeKeyring(ctx, secstore, user, keyring, 0); err != nil {
+		return nil, "", err
+	}
+	return dataKey, code, pinKeyring(secstore, user, dataKey)
+}
+
+// this function will return the data key of the user unwrapped with the key management private key
+// ErrKeyringNotVerified is returned when the keyring does not verify (see verifyKeyring)
+func UnlockKeyring(ctx context.Context, secstore SecretStore, user string, priv gocrypto.PrivateKey) ([]byte, error) {
+	keyring, _, err := readKeyring(ctx, secstore, user)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := crypto.UnwrapKey(priv, keyring.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyKeyring(ctx, secstore, user, dataKey); err != nil {
+		return nil, err
+	}
+	return dataKey, nil
+}
+
+// this function will return the data key of the user unlocked with the recovery code, the recovery code
+// authenticates the data key so it is pinned on this computer
+// when pub is set (a new Yubikey) the data key is wrapped to it, the recovery code stays the same
+func RecoverKeyring(ctx context.Context, secstore SecretStore, user, code string, pub gocrypto.PublicKey) ([]byte, error) {
+	keyring, version, err := readKeyring(ctx, secstore, user)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := crypto.NormalizeRecoveryCode(code)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := crypto.Open(crypto.DeriveKey(normalized, keyring.RecoverySalt), keyring.Recovery)
+	if err != nil {
+		return nil, errors.New("recovery code does not unlock the keyring")
+	}
+	if err := pinKeyring(secstore, user, dataKey); err != nil {
+		return nil, err
+	}
+	if pub == nil {
+		return dataKey, nil
+	}
+	if keyring.WrappedKey, err = crypto.WrapKey(pub, dataKey); err != nil {
+		return nil, err
+	}
+	return dataKey, writeKeyring(ctx, secstore, user, keyring, version)
+}
+
+// this function will return ErrZeroKnowledgeDisabled when ZEROKNOWLEDGE is set and the store is not unlocked with the
+// data key, the tools that can not unlock the keyring (no Yubikey) must not read or write the secrets
+func CheckZeroKnowledge(secstore SecretStore) error {
+	if !config.ReadZeroKnowledge() {
+		return nil
+	}
+	if _, ok := secstore.Backend.(*SealedBackend); ok {
+		return nil
+	}
+	return fmt.Errorf("ZEROKNOWLEDGE is set, the keyring is only unlocked by the interactive client: %w", ErrZeroKnowledgeDisabled)
+}
+
+// this function will encrypt the sensitive fields of the entries written before ZEROKNOWLEDGE was enabled
+// return the number of entries written
+func SealExisting(ctx context.Context, secstore SecretStore) (int, error) {
+	b, ok := secstore.Backend.(*SealedBackend)
+	if !ok {
+		return 0, ErrZeroKnowledgeDisabled
+	}
+	return rewriteEntries(ctx, secstore, b.Backend, func(data map[string]interface{}) (map[string]interface{}, bool, error) {
+		if isSealedEntry(data) {
+			return data, false, nil
+		}
+		return b.sealEntry(data)
+	}, true)
+}
@@ -1,6 +1,7 @@
 package smartcard
 
 import (
+	"crypto"
 	"crypto/x509"
 	"log"
 	"strings"
@@ -52,3 +53,26 @@ func CheckYubikey() bool {
 	}
 	return true
 }
+
+// return the public key and the private key accessor of the key management slot
+// the private key is a crypto.Decrypter (RSA) or a *piv.ECDSAPrivateKey able to do ECDH
+func KeyManagementKey(yubikey *piv.YubiKey, pin string) (crypto.PublicKey, crypto.PrivateKey, error) {
+	cert, err := yubikey.Certificate(piv.SlotKeyManagement)
+	if err != nil {
+		return nil, nil, err
+	}
+	priv, err := yubikey.PrivateKey(piv.SlotKeyManagement, cert.PublicKey, piv.KeyAuth{PIN: pin})
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert.PublicKey, priv, nil
+}
+
+// return the public key of the key management slot, no pin is needed
+func KeyManagementPublicKey(yubikey *piv.YubiKey) (crypto.PublicKey, error) {
+	cert, err := yubikey.Certificate(piv.SlotKeyManagement)
+	if err != nil {
+		return nil, err
+	}
+	return cert.PublicKey, nil
+}